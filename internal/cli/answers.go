@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"os"
+
+	"inscribe/internal/domain"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// answerFile is a saved run: the values collected for a template and the values
+// its generator functions produced, so rendering it again yields the same output.
+type answerFile struct {
+	Template  string            `yaml:"template"`
+	Values    map[string]string `yaml:"values"`
+	Generated map[string]string `yaml:"generated,omitempty"`
+}
+
+// loadAnswers reads an answer file written by saveAnswers.
+func loadAnswers(path string) (*answerFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading answers: %w", err)
+	}
+	var answers answerFile
+	if err := yaml.Unmarshal(data, &answers); err != nil {
+		return nil, fmt.Errorf("parsing answers %q: %w", path, err)
+	}
+	return &answers, nil
+}
+
+// savedValues returns the values of a run to save. Sub-templates are saved as the
+// descriptions in chosen, as picked, rather than their content. Derived values are
+// computed again when the answers are replayed, so they follow changed inputs; only
+// those given explicitly, overriding the computed value, are kept.
+func savedValues(fields []domain.FieldDefinition, values map[string]string, explicit map[string]bool, chosen map[string]string) map[string]string {
+	saved := maps.Clone(values)
+	maps.Copy(saved, chosen)
+	for _, f := range fields {
+		if f.Type == domain.FieldDerived && !explicit[f.Name] {
+			delete(saved, f.Name)
+		}
+	}
+	return saved
+}
+
+// saveAnswers writes answers to path. The file may hold generated credentials,
+// so it is only readable by its owner.
+func saveAnswers(path string, answers *answerFile) error {
+	data, err := yaml.Marshal(answers)
+	if err != nil {
+		return fmt.Errorf("encoding answers: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("writing answers to %q: %w", path, err)
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package cli
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	"inscribe/internal/domain"
@@ -27,14 +30,20 @@ type BridgeConfig struct {
 	Filename     string
 	Context      string
 	Kubeconfig   string
+	AnswersFile  string            // Answers of an earlier run to start from; flags take precedence
+	SaveAnswers  string            // Where to save the answers and generated values of this run
+	Offline      bool              // Render without cluster access: lookup finds nothing
+	Client       domain.KubeClient // Cluster access for the wizard and lookup; nil uses Kubeconfig
 }
 
 // RunBridge orchestrates the template→TUI→render→write flow.
 func RunBridge(cfg BridgeConfig) error {
-	// Validate filename and output directory early
+	// Validate filename and output directory early. A rejected filename is
+	// reported with the rejected field values.
+	var invalid domain.ValidationErrors
 	if cfg.Filename != "" {
 		if _, err := domain.NewFilename(cfg.Filename); err != nil {
-			return fmt.Errorf("invalid filename: %w", err)
+			invalid = append(invalid, domain.NewValidationError("filename", cfg.Filename, err))
 		}
 	}
 	if _, err := domain.NewPath(cfg.OutputDir); err != nil {
@@ -47,20 +56,58 @@ func RunBridge(cfg BridgeConfig) error {
 		return fmt.Errorf("loading templates from %q: %w", cfg.TemplateDir, err)
 	}
 
+	client := cfg.Client
+	if client == nil {
+		client = kubernetes.NewClient(cfg.Kubeconfig)
+	}
+
 	// 2. Parse template (pass 1) to extract fields
 	parser := engine.NewParser(reg)
+	if !cfg.Offline {
+		parser.SetKubeClient(client)
+	}
 	fields, err := parser.ExtractFields(cfg.TemplateName)
 	if err != nil {
 		return fmt.Errorf("extracting fields: %w", err)
 	}
+	rules, err := parser.Rules(cfg.TemplateName, fields)
+	if err != nil {
+		return err
+	}
+	meta, err := reg.GetTemplate(cfg.TemplateName)
+	if err != nil {
+		return err
+	}
+	if err := checkMinVersion(meta); err != nil {
+		return err
+	}
 
 	// 3. Check which fields are satisfied by flags
 	allProvided := true
 	values := make(map[string]string)
+	explicit := make(map[string]bool) // values given by flag or answers file
+	chosen := make(map[string]string) // sub-template descriptions, replaced by their content
+
+	// Start from saved answers, replaying the values generated in that run
+	if cfg.AnswersFile != "" {
+		answers, err := loadAnswers(cfg.AnswersFile)
+		if err != nil {
+			return err
+		}
+		if answers.Template != "" && answers.Template != cfg.TemplateName {
+			return fmt.Errorf("answers in %q are for template %q, not %q", cfg.AnswersFile, answers.Template, cfg.TemplateName)
+		}
+		for k, v := range answers.Values {
+			values[k] = v
+			explicit[k] = true
+		}
+		parser.Secrets().Load(answers.Generated)
+	}
 
 	// Copy provided flag values
 	for k, v := range cfg.FlagValues {
 		values[k] = v
+		explicit[k] = true
 	}
 
 	// Add context if provided
@@ -68,37 +115,82 @@ func RunBridge(cfg BridgeConfig) error {
 		values["context"] = cfg.Context
 	}
 
-	// Validate provided values and check completeness
+	// Validate provided values and check completeness. Fields with a template
+	// default count as provided, so only non-defaulted flags are required.
+	// Optional fields and fields whose condition does not hold are never required.
+	// Rejected values are collected across fields, so all of them are reported at once.
+	defaulted := make(map[string]bool)
 	for _, f := range fields {
+		// Derived fields are computed after collection; a flag only overrides them.
+		if f.Type == domain.FieldDerived {
+			continue
+		}
+
+		applies, err := parser.EvalCondition(f.Condition, values)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", f.Name, err)
+		}
+		if !applies {
+			continue
+		}
+
 		v, ok := values[f.Name]
 		if !ok || v == "" {
-			allProvided = false
-			continue
+			if f.Default == "" {
+				if !f.Optional {
+					allProvided = false
+				}
+				continue
+			}
+			v = f.Default
+			values[f.Name] = v
+			defaulted[f.Name] = true
 		}
 
 		// Validate manual fields
 		if f.Type == domain.FieldInput {
 			if _, err := domain.ParseValue(f.ValidationType, v); err != nil {
-				return fmt.Errorf("invalid value for %q: %w", f.Name, err)
+				invalid = append(invalid, domain.NewValidationError(f.Name, v, err))
+				continue
+			}
+		}
+		if f.Type == domain.FieldRepeatable {
+			if err := domain.ValidateList(f.ValidationType, v); err != nil {
+				invalid = append(invalid, domain.NewValidationError(f.Name, v, err))
+				continue
 			}
 		}
 
-		// Resolve templateGroup values by matching description to content
+		// Resolve templateGroup values by matching description to content.
+		// Multi-select groups keep the matched descriptions, one per line.
 		if f.Type == domain.FieldTemplateGroup {
 			subs, err := reg.GetSubTemplates(f.Source)
 			if err != nil {
 				return fmt.Errorf("loading sub-templates for %q: %w", f.Source, err)
 			}
-			resolved := false
-			for _, sub := range subs {
-				if matchesSubTemplate(v, sub) {
-					values[f.Name] = sub.Content
-					resolved = true
+			selections := []string{v}
+			if f.Multiple {
+				selections = domain.SplitList(v)
+			}
+			var resolved []string
+			matched := true
+			for _, selection := range selections {
+				sub, ok := findSubTemplate(subs, selection)
+				if !ok {
+					err := fmt.Errorf("no matching sub-template %q for group %q (available: %s)", selection, f.Source, listSubTemplateOptions(subs))
+					invalid = append(invalid, domain.NewValidationError(f.Name, selection, err))
+					matched = false
 					break
 				}
+				if f.Multiple {
+					resolved = append(resolved, sub.Description)
+					continue
+				}
+				resolved = append(resolved, sub.Content)
+				chosen[f.Name] = sub.Description
 			}
-			if !resolved {
-				return fmt.Errorf("no matching sub-template %q for group %q (available: %s)", v, f.Source, listSubTemplateOptions(subs))
+			if matched {
+				values[f.Name] = domain.JoinList(resolved)
 			}
 		}
 
@@ -108,23 +200,64 @@ func RunBridge(cfg BridgeConfig) error {
 			if err != nil {
 				return fmt.Errorf("loading static list %q: %w", f.Source, err)
 			}
-			found := false
-			for _, item := range list.Items {
-				if item == v {
-					found = true
+			available, err := parser.AvailableItems(list, values)
+			if err != nil {
+				return err
+			}
+			selections := []string{v}
+			if f.Multiple {
+				selections = domain.SplitList(v)
+			}
+			for _, selection := range selections {
+				if !slices.Contains(available, selection) {
+					err := fmt.Errorf("%q is not in list %q (available: %v)", selection, f.Source, available)
+					invalid = append(invalid, domain.NewValidationError(f.Name, selection, err))
 					break
 				}
 			}
-			if !found {
-				return fmt.Errorf("invalid value %q for list %q (available: %v)", v, f.Source, list.Items)
-			}
 		}
 	}
 
-	// 4. Decision: all provided → render directly, otherwise TUI
-	if !allProvided || cfg.Filename == "" {
-		client := kubernetes.NewClient(cfg.Kubeconfig)
-		result, err := tui.RunWizard(fields, values, reg, client, cfg.Filename)
+	// 4. Decision: all provided → render directly, otherwise TUI.
+	// Bundles without a filename write a file per document, and templates with an
+	// output filename pattern name their file themselves, so they need none.
+	// The wizard lets the filename be left empty for them, saying what happens.
+	filenameHint := ""
+	if meta.Bundle {
+		filenameHint = "Leave empty to write one file per document"
+	} else if meta.OutputFilename != "" {
+		filenameHint = fmt.Sprintf("Leave empty to use %s", meta.OutputFilename)
+	}
+	wizard := !allProvided || (cfg.Filename == "" && filenameHint == "")
+	if wizard {
+		// Defaults are pre-filled by the wizard rather than treated as answers,
+		// so the user can still change them.
+		for name := range defaulted {
+			delete(values, name)
+		}
+	}
+
+	// Rules on the values given so far are checked before the wizard, as it cannot
+	// change them, or with rejected values, so both are reported at once.
+	if wizard || len(invalid) > 0 {
+		given := maps.Clone(values)
+		for _, e := range invalid {
+			delete(given, e.Field)
+		}
+		err := parser.CheckRules(fields, rules, given, false)
+		var broken domain.ValidationErrors
+		if errors.As(err, &broken) {
+			invalid = append(invalid, broken...)
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := invalid.Err(); err != nil {
+		return sortValidationErrors(invalid, fields)
+	}
+
+	if wizard {
+		result, err := tui.RunWizard(fields, meta.Pages, rules, values, reg, client, parser, cfg.Filename, filenameHint)
 		if err != nil {
 			return fmt.Errorf("wizard: %w", err)
 		}
@@ -132,26 +265,134 @@ func RunBridge(cfg BridgeConfig) error {
 		cfg.Filename = result.Filename
 	}
 
-	// 5. Render template (pass 2)
+	canonicalQuantities(fields, values)
+
+	// 5. Compute derived fields from the collected values
+	if err := parser.DeriveValues(fields, values); err != nil {
+		return err
+	}
+	if err := parser.CheckRules(fields, rules, values, true); err != nil {
+		return err
+	}
+
+	if cfg.Filename == "" && !meta.Bundle && meta.OutputFilename != "" {
+		name, err := parser.Expand(meta.OutputFilename, values)
+		if err != nil {
+			return fmt.Errorf("output filename: %w", err)
+		}
+		if _, err := domain.NewFilename(name); err != nil {
+			return fmt.Errorf("output filename %q: %w", name, err)
+		}
+		cfg.Filename = name
+	}
+
+	// 6. Render template (pass 2)
 	rendered, err := parser.Render(cfg.TemplateName, values)
 	if err != nil {
 		return fmt.Errorf("rendering template: %w", err)
 	}
 
-	// 6. Write output
+	// 7. Write output: a bundle is split into its documents, written to a file each
+	// without a filename or joined into one; anything else is written as rendered.
+	manifest := rendered
+	var docs []domain.Document
+	if meta.Bundle {
+		docs = engine.SplitDocuments(rendered)
+		manifest = engine.JoinDocuments(docs)
+	}
 	writer := output.NewWriter()
-	path, err := writer.Write(rendered, cfg.OutputDir, cfg.Filename)
-	if err != nil {
-		return fmt.Errorf("writing manifest: %w", err)
+	var paths []string
+	if meta.Bundle && cfg.Filename == "" {
+		filenames, err := engine.DocumentFilenames(meta.Name, docs)
+		if err != nil {
+			return err
+		}
+		for i, doc := range docs {
+			path, err := writer.Write(doc.Content, cfg.OutputDir, filenames[i])
+			if err != nil {
+				return fmt.Errorf("writing manifest: %w", err)
+			}
+			paths = append(paths, path)
+		}
+	} else {
+		path, err := writer.Write(manifest, cfg.OutputDir, cfg.Filename)
+		if err != nil {
+			return fmt.Errorf("writing manifest: %w", err)
+		}
+		paths = append(paths, path)
 	}
 
-	fmt.Printf("Manifest written to: %s\n", path)
+	if cfg.SaveAnswers != "" {
+		err := saveAnswers(cfg.SaveAnswers, &answerFile{
+			Template:  cfg.TemplateName,
+			Values:    savedValues(fields, values, explicit, chosen),
+			Generated: parser.Secrets().Recorded(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, path := range paths {
+		fmt.Printf("Manifest written to: %s\n", path)
+	}
+	if cfg.SaveAnswers != "" {
+		fmt.Printf("Answers saved to: %s\n", cfg.SaveAnswers)
+	}
 	fmt.Println()
-	printColoredYAML(rendered)
+	// Generated credentials are written to the files but not shown on screen.
+	printColoredYAML(parser.Secrets().Mask(manifest))
 	fmt.Println()
 	return nil
 }
 
+// canonicalQuantities replaces the answers of quantity fields, such as memory and
+// cpu, with their canonical form, so " 1.5Gi " renders as 1536Mi.
+func canonicalQuantities(fields []domain.FieldDefinition, values map[string]string) {
+	for _, f := range fields {
+		v, ok := values[f.Name]
+		if f.Type != domain.FieldInput || !ok || v == "" {
+			continue
+		}
+		vo, err := domain.ParseValue(f.ValidationType, v)
+		if err != nil {
+			continue
+		}
+		if q, ok := vo.(domain.QuantityValue); ok {
+			values[f.Name] = q.String()
+		}
+	}
+}
+
+// sortValidationErrors orders errs by when their fields are asked, the filename
+// last, keeping the order of errors on the same field.
+func sortValidationErrors(errs domain.ValidationErrors, fields []domain.FieldDefinition) domain.ValidationErrors {
+	order := make(map[string]int, len(fields))
+	for i, f := range fields {
+		order[f.Name] = i
+	}
+	position := func(e *domain.ValidationError) int {
+		if i, ok := order[e.Field]; ok {
+			return i
+		}
+		return len(fields)
+	}
+	slices.SortStableFunc(errs, func(a, b *domain.ValidationError) int {
+		return position(a) - position(b)
+	})
+	return errs
+}
+
+// findSubTemplate returns the sub-template selected by value.
+func findSubTemplate(subs []domain.SubTemplateMeta, value string) (domain.SubTemplateMeta, bool) {
+	for _, sub := range subs {
+		if engine.MatchesSubTemplate(value, sub) {
+			return sub, true
+		}
+	}
+	return domain.SubTemplateMeta{}, false
+}
+
 // printColoredYAML writes syntax-highlighted YAML to stdout.
 func printColoredYAML(yaml string) {
 	var buf bytes.Buffer
@@ -217,11 +458,6 @@ func RunParentCommand(cmd *cobra.Command, commandPrefix string, tmplDir string)
 	return sub.RunE(sub, nil)
 }
 
-func matchesSubTemplate(value string, sub domain.SubTemplateMeta) bool {
-	// Match by description (case-insensitive) or exact file path
-	return strings.EqualFold(value, sub.Description) || value == sub.FilePath
-}
-
 func listSubTemplateOptions(subs []domain.SubTemplateMeta) string {
 	descs := make([]string, len(subs))
 	for i, sub := range subs {
@@ -1,10 +1,17 @@
 package cli
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+
+	"inscribe/internal/domain"
+	"inscribe/internal/kubernetes"
+
+	"go.yaml.in/yaml/v3"
 )
 
 func TestRunBridgeDirectRender(t *testing.T) {
@@ -76,6 +83,179 @@ metadata:
 	}
 }
 
+func TestRunBridgeCanonicalQuantities(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "simple.yaml"),
+		`{{/* inscribe: type="template" name="simple" command="test" description="Test" */}}
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  containers:
+    - name: app
+      resources:
+        limits:
+          memory: {{ input "mem" "memory" }}
+          cpu: {{ input "cpu" "cpu" }}
+`)
+
+	err := RunBridge(BridgeConfig{
+		TemplateName: "simple",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"mem": " 1.5Gi ",
+			"cpu": "500m ",
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "memory: 1536Mi\n") {
+		t.Errorf("output should contain canonical memory 1536Mi, got:\n%s", content)
+	}
+	if !regexp.MustCompile(`cpu: "?500m"?\n`).MatchString(content) {
+		t.Errorf("output should contain cpu 500m without whitespace, got:\n%s", content)
+	}
+}
+
+func TestRunBridgeValidationRule(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "simple.yaml"),
+		`{{/* inscribe: type="template" name="simple" command="test" description="Test" */}}
+metadata:
+  name: {{ input "name" "regex(\"^pg-\")" }}
+  comment: {{ input "comment" "string(optional)" }}
+spec:
+  instances: {{ input "instances" "integer(min=1,max=9)" }}
+`)
+
+	tests := []struct {
+		name    string
+		flags   map[string]string
+		wantErr string
+	}{
+		{"valid", map[string]string{"name": "pg-main", "instances": "3"}, ""},
+		{"above max", map[string]string{"name": "pg-main", "instances": "12"}, `invalid value for "instances": max=9: must be at most 9`},
+		{"pattern", map[string]string{"name": "main", "instances": "3"}, `invalid value for "name": regex: must match ^pg-`},
+		{"several", map[string]string{"name": "main", "instances": "12"}, "2 invalid values:\n  name: must match ^pg- (regex)\n    got \"main\"\n  instances: must be at most 9 (max=9)\n    got \"12\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RunBridge(BridgeConfig{
+				TemplateName: "simple",
+				TemplateDir:  dir,
+				OutputDir:    t.TempDir(),
+				FlagValues:   tt.flags,
+				Filename:     "output.yaml",
+			})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("RunBridge() error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("RunBridge() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunBridgeRules(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "cluster.yaml"), `{{/* inscribe:
+type: template
+name: cluster
+command: cluster test
+rules:
+  - rule: limits-memory >= requests-memory
+    message: the memory limit must be at least the memory request
+    fields: [limits-memory]
+  - instances * limits-memory <= 64Gi
+  - rule: tier != "custom" || instances % 2 == 1
+    message: custom clusters need an odd number of instances
+  - rule: name.startsWith(namespace + "-")
+    message: the name must start with the namespace and a hyphen
+    fields: [name]
+*/}}
+metadata:
+  name: {{ input "name" "dns-name" }}
+  namespace: {{ input "namespace" "dns-name" }}
+spec:
+  instances: {{ input "instances" "integer" }}
+  resources:
+    requests:
+      memory: {{ input "requests-memory" "memory(min=256Mi)" }}
+{{- if eq .tier "custom" }}
+    limits:
+      memory: {{ input "limits-memory" "memory" "optional" }}
+{{- end }}
+  tier: {{ input "tier" "string" }}
+`)
+
+	tests := []struct {
+		name    string
+		flags   map[string]string
+		wantErr string
+	}{
+		{"holds", map[string]string{"instances": "3", "requests-memory": "1Gi", "limits-memory": "2Gi", "tier": "custom"}, ""},
+		{"even instances", map[string]string{"instances": "4", "requests-memory": "1Gi", "limits-memory": "2Gi", "tier": "custom"},
+			`invalid value for "tier": custom clusters need an odd number of instances`},
+		{"even instances when not custom", map[string]string{"instances": "4", "requests-memory": "1Gi", "tier": "fixed"}, ""},
+		{"name without namespace prefix", map[string]string{"name": "orders", "instances": "3", "requests-memory": "1Gi", "limits-memory": "2Gi", "tier": "custom"},
+			`invalid value for "name": the name must start with the namespace and a hyphen`},
+		{"limit below request", map[string]string{"instances": "3", "requests-memory": "4Gi", "limits-memory": "2Gi", "tier": "custom"},
+			`invalid value for "limits-memory": the memory limit must be at least the memory request`},
+		{"total over bound", map[string]string{"instances": "5", "requests-memory": "16Gi", "limits-memory": "16Gi", "tier": "custom"},
+			`invalid value for "limits-memory": must satisfy instances * limits-memory <= 64Gi (instances * limits-memory is 80Gi)`},
+		{"several rules", map[string]string{"instances": "4", "requests-memory": "4Gi", "limits-memory": "2Gi", "tier": "custom"},
+			"2 invalid values:\n  limits-memory: the memory limit must be at least the memory request (limits-memory >= requests-memory)\n    got \"2Gi\"\n  tier: custom clusters need an odd number of instances (tier != \"custom\" || instances % 2 == 1)\n    got \"custom\""},
+		{"field whose condition does not hold", map[string]string{"instances": "3", "requests-memory": "4Gi", "limits-memory": "2Gi", "tier": "fixed"}, ""},
+		{"field left empty", map[string]string{"instances": "3", "requests-memory": "1Gi", "tier": "custom"},
+			"2 invalid values:\n  limits-memory: needs an answer for limits-memory (limits-memory >= requests-memory)\n    got \"\"\n  limits-memory: needs an answer for limits-memory (instances * limits-memory <= 64Gi)\n    got \"\""},
+		{"type bound", map[string]string{"instances": "3", "requests-memory": "128Mi", "limits-memory": "2Gi", "tier": "custom"},
+			`invalid value for "requests-memory": min=256Mi: must be at least 256Mi`},
+		{"rejected value and broken rule", map[string]string{"instances": "4", "requests-memory": "128Mi", "limits-memory": "2Gi", "tier": "custom"},
+			"2 invalid values:\n  requests-memory: must be at least 256Mi (min=256Mi)\n    got \"128Mi\"\n  tier: custom clusters need an odd number of instances (tier != \"custom\" || instances % 2 == 1)\n    got \"custom\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := map[string]string{"name": "team-a-orders", "namespace": "team-a"}
+			maps.Copy(flags, tt.flags)
+			err := RunBridge(BridgeConfig{
+				TemplateName: "cluster",
+				TemplateDir:  dir,
+				OutputDir:    t.TempDir(),
+				FlagValues:   flags,
+				Filename:     "output.yaml",
+				Offline:      true,
+			})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("RunBridge() error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("RunBridge() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestRunBridgeTemplateNotFound(t *testing.T) {
 	dir := t.TempDir()
 
@@ -199,6 +379,843 @@ method: {{ staticList "methods" }}
 	}
 }
 
+func TestRunBridgeDefaultsSkipWizard(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="with-defaults" command="test" description="Test" */}}
+name: {{ input "name" "dns-name" }}
+instances: {{ input "instances" "integer" "default=3" }}
+method: {{ staticList "methods" "default=volumeSnapshot" }}
+`)
+
+	writeFile(t, filepath.Join(dir, "methods.yaml"),
+		`{{/* inscribe: type="list" name="methods" */}}
+- barmanObjectStore
+- volumeSnapshot
+`)
+
+	err := RunBridge(BridgeConfig{
+		TemplateName: "with-defaults",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"name": "mydb",
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	content := string(data)
+	if !strings.Contains(content, "instances: 3") {
+		t.Errorf("output should contain default instances, got:\n%s", content)
+	}
+	if !strings.Contains(content, "method: volumeSnapshot") {
+		t.Errorf("output should contain default method, got:\n%s", content)
+	}
+}
+
+func TestRunBridgeFlagOverridesDefault(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="with-defaults" command="test" description="Test" */}}
+instances: {{ input "instances" "integer" "default=3" }}
+`)
+
+	err := RunBridge(BridgeConfig{
+		TemplateName: "with-defaults",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"instances": "5",
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	if !strings.Contains(string(data), "instances: 5") {
+		t.Errorf("output should contain flag value, got:\n%s", string(data))
+	}
+}
+
+func TestRunBridgeConditionalFields(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="cond" command="test" description="Test" */}}
+name: {{ input "name" "dns-name" }}
+comment: {{ input "comment" "string" "optional" }}
+{{- if eq (staticList "backups") "enabled" }}
+retention: {{ input "retention" "string" }}
+{{- end }}
+`)
+
+	writeFile(t, filepath.Join(dir, "backups.yaml"),
+		`{{/* inscribe: type="list" name="backups" */}}
+- enabled
+- disabled
+`)
+
+	t.Run("condition false", func(t *testing.T) {
+		outDir := t.TempDir()
+		err := RunBridge(BridgeConfig{
+			TemplateName: "cond",
+			TemplateDir:  dir,
+			OutputDir:    outDir,
+			FlagValues: map[string]string{
+				"name":    "mydb",
+				"backups": "disabled",
+			},
+			Filename: "output.yaml",
+		})
+		if err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		if strings.Contains(string(data), "retention") {
+			t.Errorf("output should not contain retention, got:\n%s", string(data))
+		}
+	})
+
+	t.Run("condition true", func(t *testing.T) {
+		outDir := t.TempDir()
+		err := RunBridge(BridgeConfig{
+			TemplateName: "cond",
+			TemplateDir:  dir,
+			OutputDir:    outDir,
+			FlagValues: map[string]string{
+				"name":      "mydb",
+				"backups":   "enabled",
+				"retention": "30d",
+			},
+			Filename: "output.yaml",
+		})
+		if err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		if !strings.Contains(string(data), "retention: 30d") {
+			t.Errorf("output should contain retention, got:\n%s", string(data))
+		}
+	})
+}
+
+func TestRunBridgeShowRules(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="show" command="test" description="Test" */}}
+method: {{ staticList "methods" }}
+{{- if fieldIs "methods" "barmanObjectStore" }}
+bucket: {{ input "bucket" "string" "show=methods == barmanObjectStore" }}
+{{- end }}
+schedule: {{ staticList "schedules" }}
+`)
+	writeFile(t, filepath.Join(dir, "methods.yaml"),
+		`{{/* inscribe: type="list" name="methods" */}}
+- barmanObjectStore
+- volumeSnapshot
+`)
+	writeFile(t, filepath.Join(dir, "schedules.yaml"),
+		`{{/* inscribe: type="list" name="schedules" */}}
+- daily
+- continuous # show=methods == barmanObjectStore
+`)
+
+	t.Run("hidden field not required", func(t *testing.T) {
+		outDir := t.TempDir()
+		err := RunBridge(BridgeConfig{
+			TemplateName: "show",
+			TemplateDir:  dir,
+			OutputDir:    outDir,
+			FlagValues:   map[string]string{"methods": "volumeSnapshot", "schedules": "daily"},
+			Filename:     "output.yaml",
+		})
+		if err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		if strings.Contains(string(data), "bucket") {
+			t.Errorf("output should not contain bucket, got:\n%s", string(data))
+		}
+	})
+
+	t.Run("unavailable list item", func(t *testing.T) {
+		err := RunBridge(BridgeConfig{
+			TemplateName: "show",
+			TemplateDir:  dir,
+			OutputDir:    t.TempDir(),
+			FlagValues:   map[string]string{"methods": "volumeSnapshot", "schedules": "continuous"},
+			Filename:     "output.yaml",
+		})
+		if err == nil || !strings.Contains(err.Error(), "continuous") {
+			t.Errorf("expected error for unavailable item, got %v", err)
+		}
+	})
+
+	t.Run("available list item", func(t *testing.T) {
+		err := RunBridge(BridgeConfig{
+			TemplateName: "show",
+			TemplateDir:  dir,
+			OutputDir:    t.TempDir(),
+			FlagValues:   map[string]string{"methods": "barmanObjectStore", "bucket": "s3://b", "schedules": "continuous"},
+			Filename:     "output.yaml",
+		})
+		if err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+	})
+}
+
+func TestRunBridgeAutoListAliases(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="copy" command="test" description="Test" */}}
+from: {{ autoList "source-ns" "namespace" }}
+to: {{ autoList "target-ns" "namespace" }}
+`)
+
+	err := RunBridge(BridgeConfig{
+		TemplateName: "copy",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"source-ns": "staging",
+			"target-ns": "production",
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if want := "from: staging\nto: production\n"; string(data) != want {
+		t.Errorf("output = %q, want %q", string(data), want)
+	}
+}
+
+func TestRunBridgeAnswersReplayGeneratedValues(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+	answers := filepath.Join(t.TempDir(), "answers.yaml")
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="secret" command="test" description="Test" */}}
+kind: Secret
+metadata:
+  name: {{ input "name" "dns-name" }}
+stringData:
+  password: {{ password 24 }}
+  token: {{ uuid }}
+`)
+
+	run := func(cfg BridgeConfig) string {
+		t.Helper()
+		cfg.TemplateName = "secret"
+		cfg.TemplateDir = dir
+		cfg.OutputDir = outDir
+		cfg.Filename = "output.yaml"
+		if err := RunBridge(cfg); err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		return string(data)
+	}
+
+	first := run(BridgeConfig{FlagValues: map[string]string{"name": "app"}, SaveAnswers: answers})
+	info, err := os.Stat(answers)
+	if err != nil {
+		t.Fatalf("answers not saved: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("answers mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if replayed := run(BridgeConfig{AnswersFile: answers}); replayed != first {
+		t.Errorf("replayed output differs:\n%s\nwant:\n%s", replayed, first)
+	}
+	if fresh := run(BridgeConfig{FlagValues: map[string]string{"name": "app"}}); fresh == first {
+		t.Error("expected a run without answers to generate new values")
+	}
+
+	overridden := run(BridgeConfig{AnswersFile: answers, FlagValues: map[string]string{"name": "other"}})
+	if !strings.Contains(overridden, "name: other") {
+		t.Errorf("flag should override saved answer, got:\n%s", overridden)
+	}
+
+	err = RunBridge(BridgeConfig{TemplateName: "secret", TemplateDir: dir, OutputDir: outDir, Filename: "x.yaml", AnswersFile: filepath.Join(dir, "missing.yaml")})
+	if err == nil {
+		t.Error("expected error for missing answers file")
+	}
+}
+
+func TestRunBridgeAnswersReplayChangedCondition(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+	answers := filepath.Join(t.TempDir(), "answers.yaml")
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="secret" command="test" description="Test" */}}
+{{- if eq (input "tls" "boolean") "true" }}
+ca: {{ password 8 }}
+{{- end }}
+app: {{ password 12 }}
+admin: {{ derive "admin" (password 16) }}
+`)
+
+	run := func(cfg BridgeConfig) map[string]string {
+		t.Helper()
+		cfg.TemplateName = "secret"
+		cfg.TemplateDir = dir
+		cfg.OutputDir = outDir
+		cfg.Filename = "output.yaml"
+		if err := RunBridge(cfg); err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		var out map[string]string
+		if err := yaml.Unmarshal(data, &out); err != nil {
+			t.Fatalf("parsing output: %v", err)
+		}
+		return out
+	}
+
+	first := run(BridgeConfig{FlagValues: map[string]string{"tls": "false"}, SaveAnswers: answers})
+	saved, err := loadAnswers(answers)
+	if err != nil {
+		t.Fatalf("loadAnswers() error: %v", err)
+	}
+	if len(saved.Generated) != 2 {
+		t.Errorf("expected the app and admin passwords to be recorded, got %v", saved.Generated)
+	}
+
+	replayed := run(BridgeConfig{AnswersFile: answers, FlagValues: map[string]string{"tls": "true"}})
+	if replayed["app"] != first["app"] || replayed["admin"] != first["admin"] {
+		t.Errorf("replay with a changed condition changed existing passwords: %v, want app %q and admin %q", replayed, first["app"], first["admin"])
+	}
+	if replayed["ca"] == "" || replayed["ca"] == first["app"] {
+		t.Errorf("expected a new password for ca, got %q", replayed["ca"])
+	}
+}
+
+func TestRunBridgeAnswersReplayDerivedValues(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="derived" command="test" description="Test" */}}
+name: {{ input "name" "dns-name" }}
+service: {{ derive "service" (printf "%s-rw" .name) }}
+`)
+
+	run := func(cfg BridgeConfig) string {
+		t.Helper()
+		cfg.TemplateName = "derived"
+		cfg.TemplateDir = dir
+		cfg.OutputDir = outDir
+		cfg.Filename = "output.yaml"
+		if err := RunBridge(cfg); err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		return string(data)
+	}
+
+	computed := filepath.Join(t.TempDir(), "computed.yaml")
+	run(BridgeConfig{FlagValues: map[string]string{"name": "one"}, SaveAnswers: computed})
+	answers, err := loadAnswers(computed)
+	if err != nil {
+		t.Fatalf("loadAnswers() error: %v", err)
+	}
+	if _, ok := answers.Values["service"]; ok {
+		t.Errorf("computed derived value should not be saved, got %v", answers.Values)
+	}
+	if got := run(BridgeConfig{AnswersFile: computed, FlagValues: map[string]string{"name": "two"}}); !strings.Contains(got, "service: two-rw") {
+		t.Errorf("derived value should follow the changed input, got:\n%s", got)
+	}
+
+	overridden := filepath.Join(t.TempDir(), "overridden.yaml")
+	run(BridgeConfig{FlagValues: map[string]string{"name": "one", "service": "primary"}, SaveAnswers: overridden})
+	if got := run(BridgeConfig{AnswersFile: overridden, FlagValues: map[string]string{"name": "two"}}); !strings.Contains(got, "service: primary") {
+		t.Errorf("overridden derived value should be replayed, got:\n%s", got)
+	}
+}
+
+func TestRunBridgeAnswersSubTemplate(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+	saved := filepath.Join(t.TempDir(), "answers.yaml")
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="with-sub" command="test" description="Test" */}}
+resources:
+{{ templateGroup "resources" | indent 2 }}
+`)
+	writeFile(t, filepath.Join(dir, "res-prod.yaml"),
+		`{{/* inscribe: type="sub-template" group="resources" description="Production" */}}
+memory: 4Gi
+`)
+
+	run := func(cfg BridgeConfig) string {
+		t.Helper()
+		cfg.TemplateName = "with-sub"
+		cfg.TemplateDir = dir
+		cfg.OutputDir = outDir
+		cfg.Filename = "output.yaml"
+		if err := RunBridge(cfg); err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		return string(data)
+	}
+
+	first := run(BridgeConfig{FlagValues: map[string]string{"resources": "Production"}, SaveAnswers: saved})
+	answers, err := loadAnswers(saved)
+	if err != nil {
+		t.Fatalf("loadAnswers() error: %v", err)
+	}
+	if got := answers.Values["resources"]; got != "Production" {
+		t.Errorf("saved resources = %q, want the sub-template description", got)
+	}
+	if replayed := run(BridgeConfig{AnswersFile: saved}); replayed != first {
+		t.Errorf("replayed output differs:\n%s\nwant:\n%s", replayed, first)
+	}
+}
+
+func TestRunBridgeHeaderMetadata(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "named.yaml"),
+		`{{/* inscribe:
+type: template
+name: named
+command: test named
+outputFilename: "{{ .name }}-config.yaml"
+*/}}
+name: {{ input "name" "dns-name" }}
+`)
+	writeFile(t, filepath.Join(dir, "future.yaml"),
+		`{{/* inscribe:
+type: template
+name: future
+command: test future
+minInscribeVersion: 99.0.0
+*/}}
+name: {{ input "name" "dns-name" }}
+`)
+
+	t.Run("output filename pattern", func(t *testing.T) {
+		outDir := t.TempDir()
+		err := RunBridge(BridgeConfig{
+			TemplateName: "named",
+			TemplateDir:  dir,
+			OutputDir:    outDir,
+			FlagValues:   map[string]string{"name": "orders"},
+		})
+		if err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(outDir, "orders-config.yaml")); err != nil {
+			t.Errorf("expected output named by the pattern: %v", err)
+		}
+	})
+
+	t.Run("filename flag wins over pattern", func(t *testing.T) {
+		outDir := t.TempDir()
+		err := RunBridge(BridgeConfig{
+			TemplateName: "named",
+			TemplateDir:  dir,
+			OutputDir:    outDir,
+			FlagValues:   map[string]string{"name": "orders"},
+			Filename:     "custom.yaml",
+		})
+		if err != nil {
+			t.Fatalf("RunBridge() error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(outDir, "custom.yaml")); err != nil {
+			t.Errorf("expected output named by the flag: %v", err)
+		}
+	})
+
+	t.Run("min inscribe version", func(t *testing.T) {
+		err := RunBridge(BridgeConfig{
+			TemplateName: "future",
+			TemplateDir:  dir,
+			OutputDir:    t.TempDir(),
+			FlagValues:   map[string]string{"name": "orders"},
+			Filename:     "out.yaml",
+		})
+		if err == nil || !strings.Contains(err.Error(), "requires inscribe 99.0.0") {
+			t.Errorf("expected version error, got %v", err)
+		}
+	})
+}
+
+func TestRunBridgeLookup(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "restore.yaml"),
+		`{{/* inscribe: type="template" name="restore" command="test restore" */}}
+{{- $source := lookup "postgresql.cnpg.io/v1" "Cluster" "app-prod" "main-db" -}}
+name: {{ input "name" "dns-name" }}
+storageClass: {{ if $source }}{{ $source.spec.storage.storageClass }}{{ else }}standard{{ end }}
+`)
+
+	tests := []struct {
+		name    string
+		offline bool
+		want    string
+	}{
+		{"online", false, "storageClass: fast-ssd"},
+		{"offline", true, "storageClass: standard"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			err := RunBridge(BridgeConfig{
+				TemplateName: "restore",
+				TemplateDir:  dir,
+				OutputDir:    outDir,
+				FlagValues:   map[string]string{"name": "orders"},
+				Filename:     "out.yaml",
+				Context:      "production",
+				Offline:      tt.offline,
+				Client:       kubernetes.NewMockClient(),
+			})
+			if err != nil {
+				t.Fatalf("RunBridge() error: %v", err)
+			}
+			content, err := os.ReadFile(filepath.Join(outDir, "out.yaml"))
+			if err != nil {
+				t.Fatalf("reading output: %v", err)
+			}
+			if !strings.Contains(string(content), tt.want) {
+				t.Errorf("output missing %q:\n%s", tt.want, content)
+			}
+		})
+	}
+}
+
+func TestRunBridgeSubTemplateFields(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="with-sub" command="test" description="Test" */}}
+spec:
+{{ templateGroup "resources" | indent 2 }}
+`)
+	writeFile(t, filepath.Join(dir, "res-prod.yaml"),
+		`{{/* inscribe: type="sub-template" group="resources" description="Production" */}}
+storage: {{ input "storage-size" "memory" }}
+`)
+	writeFile(t, filepath.Join(dir, "res-test.yaml"),
+		`{{/* inscribe: type="sub-template" group="resources" description="Test" */}}
+storage: 1Gi
+`)
+
+	err := RunBridge(BridgeConfig{
+		TemplateName: "with-sub",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"resources":    "Production",
+			"storage-size": "20Gi",
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if !strings.Contains(string(data), "  storage: 20Gi") {
+		t.Errorf("output should contain sub-template field, got:\n%s", string(data))
+	}
+
+	// The Test profile declares no fields, so storage-size is not required.
+	err = RunBridge(BridgeConfig{
+		TemplateName: "with-sub",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"resources": "Test",
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+}
+
+func TestRunBridgeDerivedFields(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="derived" command="test" description="Test" */}}
+name: {{ input "name" "dns-name" }}
+service: {{ derive "service" (printf "%s-rw" (input "name" "dns-name")) }}
+secret: {{ derive "secret" (printf "%s-app" .name) }}
+`)
+
+	outDir := t.TempDir()
+	err := RunBridge(BridgeConfig{
+		TemplateName: "derived",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"name":   "mydb",
+			"secret": "shared-secret",
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "service: mydb-rw") {
+		t.Errorf("output should contain derived service, got:\n%s", content)
+	}
+	if !strings.Contains(content, "secret: shared-secret") {
+		t.Errorf("output should contain overridden secret, got:\n%s", content)
+	}
+}
+
+func TestRunBridgeRepeatableField(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="roles" command="test" description="Test" */}}
+roles:
+{{- range inputList "roles" "dns-name" }}
+  - {{ . }}
+{{- end }}
+`)
+
+	outDir := t.TempDir()
+	err := RunBridge(BridgeConfig{
+		TemplateName: "roles",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"roles": domain.JoinList([]string{"app", "readonly"}),
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if !strings.Contains(string(data), "  - app\n  - readonly") {
+		t.Errorf("output should contain both roles, got:\n%s", string(data))
+	}
+
+	err = RunBridge(BridgeConfig{
+		TemplateName: "roles",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"roles": domain.JoinList([]string{"app", "Not_Valid"}),
+		},
+		Filename: "output.yaml",
+	})
+	if err == nil {
+		t.Error("expected validation error for invalid role entry")
+	}
+}
+
+func TestRunBridgeMultiSelect(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="multi" command="test" description="Test" */}}
+methods:
+{{ staticList "methods" "multi" | indent 2 }}
+`)
+	writeFile(t, filepath.Join(dir, "methods.yaml"),
+		`{{/* inscribe: type="list" name="methods" */}}
+- barmanObjectStore
+- volumeSnapshot`)
+
+	outDir := t.TempDir()
+	err := RunBridge(BridgeConfig{
+		TemplateName: "multi",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"methods": domain.JoinList([]string{"barmanObjectStore", "volumeSnapshot"}),
+		},
+		Filename: "output.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "output.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if !strings.Contains(string(data), "  - barmanObjectStore\n  - volumeSnapshot") {
+		t.Errorf("output should contain both methods, got:\n%s", string(data))
+	}
+
+	err = RunBridge(BridgeConfig{
+		TemplateName: "multi",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues: map[string]string{
+			"methods": domain.JoinList([]string{"volumeSnapshot", "tape"}),
+		},
+		Filename: "output.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), `"tape"`) {
+		t.Errorf("expected error naming the invalid selection, got %v", err)
+	}
+}
+
+func TestRunBridgeMultiDocumentWrittenAsRendered(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+
+	// Not a bundle, so the output is not split into documents and joined again.
+	content := "---\nkind: ConfigMap\nname: {{ input \"name\" \"dns-name\" }}\n\n\n--- # the secret\nkind: Secret\n"
+	writeFile(t, filepath.Join(dir, "multi.yaml"),
+		`{{/* inscribe: type="template" name="multi" command="test" description="Test" */}}`+"\n"+content)
+
+	err := RunBridge(BridgeConfig{
+		TemplateName: "multi",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues:   map[string]string{"name": "orders"},
+		Filename:     "out.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "out.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if want := strings.ReplaceAll(content, `{{ input "name" "dns-name" }}`, "orders"); string(data) != want {
+		t.Errorf("out.yaml = %q, want %q", string(data), want)
+	}
+}
+
+func TestRunBridgeBundle(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "bundle.yaml"),
+		`{{/* inscribe: type="template" name="bundle" command="test" description="Test" bundle="true" */}}
+{{ outputFile (printf "%s-cluster.yaml" (input "name" "dns-name")) }}
+kind: Cluster
+name: {{ .name }}
+---
+kind: ScheduledBackup
+cluster: {{ .name }}
+`)
+
+	outDir := t.TempDir()
+	err := RunBridge(BridgeConfig{
+		TemplateName: "bundle",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues:   map[string]string{"name": "orders"},
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+
+	files := map[string]string{
+		"orders-cluster.yaml": "kind: Cluster\nname: orders\n",
+		"bundle-2.yaml":       "kind: ScheduledBackup\ncluster: orders\n",
+	}
+	for name, want := range files {
+		data, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, string(data), want)
+		}
+	}
+
+	// With a filename, the bundle is written as a single multi-document file.
+	err = RunBridge(BridgeConfig{
+		TemplateName: "bundle",
+		TemplateDir:  dir,
+		OutputDir:    outDir,
+		FlagValues:   map[string]string{"name": "orders"},
+		Filename:     "all.yaml",
+	})
+	if err != nil {
+		t.Fatalf("RunBridge() error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "all.yaml"))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if want := "kind: Cluster\nname: orders\n---\nkind: ScheduledBackup\ncluster: orders\n"; string(data) != want {
+		t.Errorf("all.yaml = %q, want %q", string(data), want)
+	}
+}
+
 func writeFile(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
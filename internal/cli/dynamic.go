@@ -1,9 +1,15 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"inscribe/internal/domain"
 	"inscribe/internal/engine"
@@ -11,14 +17,28 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// now returns the current time, from which help text counts upcoming schedule runs.
+var now = time.Now
+
+// warnings receives why templates could not be turned into commands.
+var warnings io.Writer = os.Stderr
+
 // BuildDynamicCommands loads the template registry from dir and builds
 // cobra commands dynamically from the registered templates.
-// Returns nil gracefully if dir is invalid or contains no templates.
+// Returns nil gracefully if dir is missing or contains no templates. Templates
+// the registry skipped, or a directory that cannot be loaded, are reported to
+// warnings.
 func BuildDynamicCommands(dir string) []*cobra.Command {
 	reg, err := engine.NewRegistry(dir)
 	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(warnings, "inscribe: %v\n", err)
+		}
 		return nil
 	}
+	for _, err := range reg.Skipped() {
+		fmt.Fprintf(warnings, "inscribe: %v\n", err)
+	}
 
 	templates := reg.ListTemplates()
 	if len(templates) == 0 {
@@ -79,27 +99,43 @@ func buildLeafCommand(reg domain.TemplateRegistry, tmpl domain.TemplateMeta, dir
 		fields = nil
 	}
 
-	// Storage for flag values — one per field plus context, kubeconfig, and filename
+	// Storage for flag values — one per field plus context, kubeconfig, filename and answer files.
+	// Repeatable and multi-select fields take the flag multiple times, so they get slice storage.
 	flagVars := make(map[string]*string)
+	listFlagVars := make(map[string]*[]string)
 	for _, f := range fields {
+		if f.Type == domain.FieldRepeatable || f.Multiple {
+			listFlagVars[f.Name] = &[]string{}
+			continue
+		}
 		val := ""
 		flagVars[f.Name] = &val
 	}
-	var context, kubeconfig, filename string
+	var context, kubeconfig, filename, answers, saveAnswers string
+	var offline bool
+	errorFormat := "text"
 
 	cmd := &cobra.Command{
 		Use:   leafName,
 		Short: tmpl.Description,
-		Long:  tmpl.Description,
+		Long:  tmpl.Description + templateDetails(tmpl),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if errorFormat != "text" && errorFormat != "json" {
+				return fmt.Errorf("invalid error format %q (available: text, json)", errorFormat)
+			}
 			flagValues := make(map[string]string)
 			for name, ptr := range flagVars {
 				if cmd.Flags().Changed(name) {
 					flagValues[name] = *ptr
 				}
 			}
+			for name, ptr := range listFlagVars {
+				if cmd.Flags().Changed(name) {
+					flagValues[name] = domain.JoinList(*ptr)
+				}
+			}
 
-			return RunBridge(BridgeConfig{
+			err := RunBridge(BridgeConfig{
 				TemplateName: tmpl.Name,
 				TemplateDir:  dir,
 				OutputDir:    outputDir,
@@ -107,7 +143,11 @@ func buildLeafCommand(reg domain.TemplateRegistry, tmpl domain.TemplateMeta, dir
 				Filename:     filename,
 				Context:      context,
 				Kubeconfig:   kubeconfig,
+				AnswersFile:  answers,
+				SaveAnswers:  saveAnswers,
+				Offline:      offline,
 			})
+			return reportValidationErrors(cmd, errorFormat, err)
 		},
 	}
 
@@ -120,6 +160,14 @@ func buildLeafCommand(reg domain.TemplateRegistry, tmpl domain.TemplateMeta, dir
 			continue
 		}
 		registered[f.Name] = true
+		if ptr, ok := listFlagVars[f.Name]; ok && f.Multiple {
+			cmd.Flags().StringSliceVar(ptr, f.Name, nil, flagDescription(reg, f))
+			continue
+		}
+		if ptr, ok := listFlagVars[f.Name]; ok {
+			cmd.Flags().StringArrayVar(ptr, f.Name, nil, flagDescription(reg, f))
+			continue
+		}
 		cmd.Flags().StringVar(flagVars[f.Name], f.Name, "", flagDescription(reg, f))
 	}
 
@@ -127,34 +175,146 @@ func buildLeafCommand(reg domain.TemplateRegistry, tmpl domain.TemplateMeta, dir
 	cmd.Flags().StringVar(&context, "context", "", "Kubernetes context")
 	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file")
 	cmd.Flags().StringVar(&filename, "filename", "", "Output filename")
+	cmd.Flags().StringVar(&answers, "answers", "", "Answers file of an earlier run to reuse, including generated values")
+	cmd.Flags().StringVar(&saveAnswers, "save-answers", "", "Save this run's answers and generated values to a file")
+	cmd.Flags().BoolVar(&offline, "offline", false, "Render without cluster access; lookup finds nothing")
+	cmd.Flags().StringVar(&errorFormat, "error-format", errorFormat, "How to report invalid values: text or json")
 
 	return cmd
 }
 
-// flagDescription generates help text for a dynamic flag based on its field type.
+// templateDetails lists the header metadata of a template for its help text,
+// one "Key: value" line each, or returns "" if it declares none.
+func templateDetails(tmpl domain.TemplateMeta) string {
+	var lines []string
+	add := func(key, value string) {
+		if value != "" {
+			lines = append(lines, key+": "+value)
+		}
+	}
+	add("Version", tmpl.Version)
+	add("Authors", strings.Join(tmpl.Authors, ", "))
+	add("Tags", strings.Join(tmpl.Tags, ", "))
+	add("Requires CRDs", strings.Join(tmpl.RequiresCRDs, ", "))
+	if tmpl.MinInscribeVersion != "" {
+		add("Requires inscribe", tmpl.MinInscribeVersion+" or newer")
+	}
+	add("Output file", tmpl.OutputFilename)
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n" + strings.Join(lines, "\n")
+}
+
+// flagDescription generates help text for a dynamic flag based on its field type,
+// led by the template's help text and noting its example and default when declared.
+// A default cron schedule is followed by its next runs.
 func flagDescription(reg domain.TemplateRegistry, f domain.FieldDefinition) string {
+	desc := fieldTypeDescription(reg, f)
+	if f.Placeholder != "" {
+		desc += fmt.Sprintf(" (e.g. %s)", f.Placeholder)
+	}
+	if f.Default != "" {
+		desc += fmt.Sprintf(" (default %q)", f.Default)
+		if upcoming := domain.CronUpcoming(f.ValidationType, f.Default, now()); upcoming != "" {
+			desc += "; next runs " + upcoming
+		}
+	}
+	return desc
+}
+
+// fieldTypeDescription describes the accepted values for a field based on its type.
+func fieldTypeDescription(reg domain.TemplateRegistry, f domain.FieldDefinition) string {
+	subject := f.Description
 	switch f.Type {
 	case domain.FieldInput:
-		return fmt.Sprintf("Value for %s (validated as %s)", f.Name, f.ValidationType)
+		if subject == "" {
+			subject = "Value for " + f.Name
+		}
+		return fmt.Sprintf("%s (validated as %s)", subject, f.ValidationType)
 	case domain.FieldAutoList:
-		return fmt.Sprintf("Value for %s (auto-listed from cluster if omitted)", f.Source)
+		if subject == "" {
+			subject = "Value for " + f.Name
+		}
+		if f.Name != f.Source {
+			return fmt.Sprintf("%s (a %s, auto-listed from cluster if omitted)", subject, f.Source)
+		}
+		return fmt.Sprintf("%s (auto-listed from cluster if omitted)", subject)
+	case domain.FieldRepeatable:
+		if subject == "" {
+			subject = "Entry for " + f.Name
+		}
+		if schema, ok := domain.ParseObjectSchema(f.ValidationType); ok {
+			keys := make([]string, len(schema))
+			for i, field := range schema {
+				keys[i] = fmt.Sprintf("%s=<%s>", field.Name, field.ValidationType)
+			}
+			return fmt.Sprintf("%s as %s; repeat the flag for each entry", subject, strings.Join(keys, ","))
+		}
+		return fmt.Sprintf("%s (each validated as %s); repeat the flag for each entry", subject, f.ValidationType)
+	case domain.FieldDerived:
+		if subject == "" {
+			subject = "Override for " + f.Name
+		}
+		return fmt.Sprintf("%s (derived as %s if omitted)", subject, f.Expression)
 	case domain.FieldTemplateGroup:
 		subs, err := reg.GetSubTemplates(f.Source)
 		if err != nil {
-			return fmt.Sprintf("Template group: %s", f.Source)
+			return withSubject(subject, fmt.Sprintf("Template group: %s", f.Source))
 		}
 		var descs []string
 		for _, s := range subs {
 			descs = append(descs, fmt.Sprintf("%q", s.Description))
 		}
-		return fmt.Sprintf("One of: %s", strings.Join(descs, ", "))
+		return withSubject(subject, fmt.Sprintf("%s: %s", choicePrefix(f), strings.Join(descs, ", ")))
 	case domain.FieldStaticList:
 		list, err := reg.GetStaticList(f.Source)
 		if err != nil {
-			return fmt.Sprintf("Static list: %s", f.Source)
+			return withSubject(subject, fmt.Sprintf("Static list: %s", f.Source))
 		}
-		return fmt.Sprintf("One of: %s", strings.Join(list.Items, ", "))
+		return withSubject(subject, fmt.Sprintf("%s: %s", choicePrefix(f), strings.Join(list.Items, ", ")))
 	default:
-		return f.Name
+		return withSubject(subject, f.Name)
+	}
+}
+
+// choicePrefix introduces the available choices, noting how to pass several
+// for multi-select fields.
+func choicePrefix(f domain.FieldDefinition) string {
+	if f.Multiple {
+		return "Comma-separated or repeated, any of"
+	}
+	return "One of"
+}
+
+// withSubject prefixes a choice description with the field's help text, if any.
+func withSubject(subject, choices string) string {
+	if subject == "" {
+		return choices
+	}
+	return fmt.Sprintf("%s. %s", strings.TrimSuffix(subject, "."), choices)
+}
+
+// reportValidationErrors reports the values a run rejected in the given format.
+// Rejected values are not a usage mistake, so usage is not printed for them, and
+// the error is left to main to print once. In json format they are also written
+// to stdout as {"errors": [...]} for tooling.
+func reportValidationErrors(cmd *cobra.Command, format string, err error) error {
+	var invalid domain.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	if format != "json" {
+		return err
+	}
+	data, jsonErr := json.MarshalIndent(struct {
+		Errors domain.ValidationErrors `json:"errors"`
+	}{invalid}, "", "  ")
+	if jsonErr != nil {
+		return fmt.Errorf("encoding errors: %w", jsonErr)
 	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	return err
 }
@@ -1,9 +1,16 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"inscribe/internal/domain"
 )
 
 func TestBuildDynamicCommandsTreeStructure(t *testing.T) {
@@ -143,6 +150,51 @@ func TestBuildDynamicCommandsInvalidDir(t *testing.T) {
 	}
 }
 
+func TestBuildDynamicCommandsSkipsBrokenExtends(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(saved io.Writer) { warnings = saved }(warnings)
+	warnings = &buf
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "good.yaml"),
+		`{{/* inscribe: type="template" name="good" command="cluster good" description="Good" */}}
+kind: ConfigMap
+`)
+	writeFile(t, filepath.Join(dir, "bad.yaml"),
+		`{{/* inscribe: type="template" name="bad" command="cluster bad" description="Bad" extends="missing" */}}
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	if len(cmds) != 1 {
+		t.Fatalf("expected 1 parent command, got %d", len(cmds))
+	}
+	if _, _, err := cmds[0].Find([]string{"good"}); err != nil {
+		t.Errorf("finding good command: %v", err)
+	}
+	for _, sub := range cmds[0].Commands() {
+		if sub.Name() == "bad" {
+			t.Error("expected the template with a broken extends to be skipped")
+		}
+	}
+	want := `inscribe: skipping template "bad": template "bad" extends unknown template "missing"` + "\n"
+	if buf.String() != want {
+		t.Errorf("warnings = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestBuildDynamicCommandsInvalidDirQuiet(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(saved io.Writer) { warnings = saved }(warnings)
+	warnings = &buf
+
+	if cmds := BuildDynamicCommands(filepath.Join(t.TempDir(), "missing")); cmds != nil {
+		t.Errorf("expected nil for a missing dir, got %d commands", len(cmds))
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no warning for a missing dir, got %q", buf.String())
+	}
+}
+
 func TestBuildDynamicCommandsEmptyDir(t *testing.T) {
 	dir := t.TempDir()
 	cmds := BuildDynamicCommands(dir)
@@ -254,6 +306,56 @@ ns: {{ autoList "namespace" }}
 	}
 }
 
+func TestBuildDynamicCommandsAutoListAliases(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
+		`{{/* inscribe: type="template" name="test" command="test cmd" description="Test" */}}
+from: {{ autoList "source-ns" "namespace" }}
+to: {{ autoList "target-ns" "namespace" }}
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	leaf, _, _ := cmds[0].Find([]string{"cmd"})
+	for _, name := range []string{"source-ns", "target-ns"} {
+		f := leaf.Flags().Lookup(name)
+		if f == nil {
+			t.Fatalf("expected --%s flag", name)
+		}
+		if !strings.Contains(f.Usage, name) || !strings.Contains(f.Usage, "a namespace") {
+			t.Errorf("expected --%s description to name the field and its source, got %q", name, f.Usage)
+		}
+	}
+	if leaf.Flags().Lookup("namespace") != nil {
+		t.Error("aliased autoList fields should not register a --namespace flag")
+	}
+}
+
+func TestBuildDynamicCommandsTemplateDetails(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
+		`{{/* inscribe:
+type: template
+name: test
+command: test cmd
+description: Test
+version: 1.2.0
+tags: [postgres, cnpg]
+requiresCRDs: [clusters.postgresql.cnpg.io]
+*/}}
+name: {{ input "name" "dns-name" }}
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	leaf, _, _ := cmds[0].Find([]string{"cmd"})
+	want := "Test\n\nVersion: 1.2.0\nTags: postgres, cnpg\nRequires CRDs: clusters.postgresql.cnpg.io"
+	if leaf.Long != want {
+		t.Errorf("Long = %q, want %q", leaf.Long, want)
+	}
+	if leaf.Flags().Lookup("name") == nil {
+		t.Error("expected --name flag")
+	}
+}
+
 func TestBuildDynamicCommandsDuplicateFields(t *testing.T) {
 	dir := t.TempDir()
 	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
@@ -273,3 +375,176 @@ ref: prefix-{{ input "username" "string" }}-suffix
 		t.Fatal("expected --username flag")
 	}
 }
+
+func TestFlagDescriptionDefault(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
+		`{{/* inscribe: type="template" name="test" command="test cmd" description="Test" */}}
+instances: {{ input "instances" "integer" "default=3" }}
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	leaf, _, _ := cmds[0].Find([]string{"cmd"})
+	f := leaf.Flags().Lookup("instances")
+	if f == nil {
+		t.Fatal("expected --instances flag")
+	}
+
+	if !strings.Contains(f.Usage, `(default "3")`) {
+		t.Errorf("expected flag description to mention default, got %q", f.Usage)
+	}
+}
+
+func TestFlagDescriptionCronDefault(t *testing.T) {
+	defer func(saved func() time.Time) { now = saved }(now)
+	now = func() time.Time { return time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC) }
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
+		`{{/* inscribe: type="template" name="test" command="test cmd" description="Test" */}}
+schedule: "{{ input "schedule" "cron-schedule(seconds)" "default=0 0 0 * * *" }}"
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	leaf, _, _ := cmds[0].Find([]string{"cmd"})
+	f := leaf.Flags().Lookup("schedule")
+	if f == nil {
+		t.Fatal("expected --schedule flag")
+	}
+
+	want := "next runs Sun 18 Oct 2026 00:00:00, Mon 19 Oct 2026 00:00:00, Tue 20 Oct 2026 00:00:00 (UTC)"
+	if !strings.Contains(f.Usage, want) {
+		t.Errorf("expected flag description to contain %q, got %q", want, f.Usage)
+	}
+}
+
+func TestFlagDescriptionHelpText(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
+		`{{/* inscribe: type="template" name="test" command="test cmd" description="Test" */}}
+name: {{ input "name" "dns-name" "help=Name of the cluster" "placeholder=orders-db" }}
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	leaf, _, _ := cmds[0].Find([]string{"cmd"})
+	f := leaf.Flags().Lookup("name")
+	if f == nil {
+		t.Fatal("expected --name flag")
+	}
+
+	for _, want := range []string{"Name of the cluster", "dns-name", "orders-db"} {
+		if !strings.Contains(f.Usage, want) {
+			t.Errorf("expected flag description to contain %q, got %q", want, f.Usage)
+		}
+	}
+}
+
+func TestBuildDynamicCommandsRepeatableFlag(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
+		`{{/* inscribe: type="template" name="test" command="test cmd" description="Test" */}}
+{{- range inputList "roles" "dns-name" }}
+- {{ . }}
+{{- end }}
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	leaf, _, _ := cmds[0].Find([]string{"cmd"})
+	f := leaf.Flags().Lookup("roles")
+	if f == nil {
+		t.Fatal("expected --roles flag")
+	}
+	if f.Value.Type() != "stringArray" {
+		t.Errorf("expected --roles to be repeatable, got type %q", f.Value.Type())
+	}
+	if err := leaf.Flags().Parse([]string{"--roles", "app", "--roles", "readonly"}); err != nil {
+		t.Fatalf("parsing flags: %v", err)
+	}
+	if got := f.Value.String(); got != "[app,readonly]" {
+		t.Errorf("--roles value = %q, want [app,readonly]", got)
+	}
+}
+
+func TestBuildDynamicCommandsMultiSelectFlag(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"),
+		`{{/* inscribe: type="template" name="test" command="test cmd" description="Test" */}}
+{{ staticList "methods" "multi" }}
+`)
+	writeFile(t, filepath.Join(dir, "methods.yaml"),
+		`{{/* inscribe: type="list" name="methods" */}}
+- a
+- b`)
+
+	cmds := BuildDynamicCommands(dir)
+	leaf, _, _ := cmds[0].Find([]string{"cmd"})
+	f := leaf.Flags().Lookup("methods")
+	if f == nil {
+		t.Fatal("expected --methods flag")
+	}
+	if f.Value.Type() != "stringSlice" {
+		t.Errorf("expected --methods to accept several values, got type %q", f.Value.Type())
+	}
+	if !strings.Contains(f.Usage, "any of: a, b") {
+		t.Errorf("usage should list the choices, got %q", f.Usage)
+	}
+	if err := leaf.Flags().Parse([]string{"--methods", "a,b"}); err != nil {
+		t.Fatalf("parsing flags: %v", err)
+	}
+	if got := f.Value.String(); got != "[a,b]" {
+		t.Errorf("--methods value = %q, want [a,b]", got)
+	}
+}
+
+func TestBuildDynamicCommandsErrorFormatJSON(t *testing.T) {
+	defer func(saved string) { outputDir = saved }(outputDir)
+	outputDir = t.TempDir()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tmpl.yaml"), `{{/* inscribe:
+type: template
+name: test
+command: test cmd
+rules:
+  - rule: replicas % 2 == 1
+    message: replicas must be odd
+*/}}
+name: {{ input "name" "dns-name" }}
+instances: {{ input "instances" "integer(min=1,max=9)" }}
+replicas: {{ input "replicas" "integer" }}
+`)
+
+	cmds := BuildDynamicCommands(dir)
+	var stdout bytes.Buffer
+	cmds[0].SetOut(&stdout)
+	cmds[0].SetErr(io.Discard)
+	cmds[0].SetArgs([]string{"cmd", "--name", "Main", "--instances", "12", "--replicas", "2", "--filename", "../out.yaml", "--offline", "--error-format", "json"})
+	if err := cmds[0].Execute(); err == nil {
+		t.Fatal("expected an error for invalid values")
+	}
+
+	var got struct {
+		Errors []domain.ValidationError `json:"errors"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("decoding %q: %v", stdout.String(), err)
+	}
+	var fields []string
+	for _, e := range got.Errors {
+		fields = append(fields, e.Field)
+	}
+	if !slices.Equal(fields, []string{"name", "instances", "replicas", "filename"}) {
+		t.Fatalf("expected errors for name, instances, replicas and filename, got %+v", got.Errors)
+	}
+	instances := got.Errors[1]
+	if instances.Field != "instances" || instances.Rule != "max=9" || instances.Value != "12" || instances.Message != "must be at most 9" {
+		t.Errorf("unexpected error for instances: %+v", instances)
+	}
+	replicas := got.Errors[2]
+	if replicas.Rule != "replicas % 2 == 1" || replicas.Value != "2" || replicas.Message != "replicas must be odd" {
+		t.Errorf("unexpected error for replicas: %+v", replicas)
+	}
+	if filename := got.Errors[3]; filename.Value != "../out.yaml" {
+		t.Errorf("unexpected error for filename: %+v", filename)
+	}
+}
@@ -14,9 +14,10 @@ var (
 // NewRootCmd creates the root inscribe command.
 func NewRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "inscribe",
-		Short: "Generate Kubernetes manifests from templates",
-		Long:  "Inscribe is an interactive CLI tool for generating Kubernetes manifest files via templating.",
+		Use:     "inscribe",
+		Short:   "Generate Kubernetes manifests from templates",
+		Long:    "Inscribe is an interactive CLI tool for generating Kubernetes manifest files via templating.",
+		Version: Version,
 	}
 
 	cmd.PersistentFlags().StringVar(&templateDir, "template-dir", getEnvOrDefault("INSCRIBE_TEMPLATE_DIR", "template_examples"), "Path to template directory")
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"inscribe/internal/domain"
+)
+
+// Version is the inscribe release, checked against the minInscribeVersion declared
+// by templates. Release builds set it with
+// -ldflags "-X inscribe/internal/cli.Version=<version>".
+var Version = "0.1.0"
+
+// checkMinVersion fails if the template needs a newer inscribe than this one.
+func checkMinVersion(meta *domain.TemplateMeta) error {
+	if meta.MinInscribeVersion == "" {
+		return nil
+	}
+	cmp, err := compareVersions(Version, meta.MinInscribeVersion)
+	if err != nil {
+		return fmt.Errorf("template %q: minInscribeVersion: %w", meta.Name, err)
+	}
+	if cmp < 0 {
+		return fmt.Errorf("template %q requires inscribe %s or newer, this is %s", meta.Name, meta.MinInscribeVersion, Version)
+	}
+	return nil
+}
+
+// compareVersions compares two dotted release versions such as "1.2.0" or "v1.2",
+// returning -1, 0 or 1. Missing components count as 0 and pre-release or build
+// suffixes ("-rc.1", "+abc") are ignored.
+func compareVersions(a, b string) (int, error) {
+	pa, err := versionParts(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := versionParts(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func versionParts(v string) ([]int, error) {
+	core := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
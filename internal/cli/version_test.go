@@ -0,0 +1,37 @@
+package cli
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    int
+		wantErr bool
+	}{
+		{a: "0.1.0", b: "0.1.0", want: 0},
+		{a: "0.1.0", b: "0.2.0", want: -1},
+		{a: "1.10.0", b: "1.9.3", want: 1},
+		{a: "v1.2", b: "1.2.0", want: 0},
+		{a: "1.2.0-rc.1", b: "1.2.0", want: 0},
+		{a: "2", b: "1.99", want: 1},
+		{a: "1.x", b: "1.0", wantErr: true},
+		{a: "1.0", b: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			got, err := compareVersions(tt.a, tt.b)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error comparing %q and %q", tt.a, tt.b)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compareVersions() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
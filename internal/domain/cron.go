@@ -0,0 +1,323 @@
+package domain
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronDialect is a flavour of cron expression, differing in the fields it has.
+// Every dialect accepts @daily-style macros and month and weekday names.
+type CronDialect string
+
+const (
+	// CronStandard has five fields, minute hour day month weekday, as Kubernetes
+	// CronJobs use.
+	CronStandard CronDialect = "standard"
+	// CronSeconds leads with a seconds field, second minute hour day month weekday,
+	// as CNPG ScheduledBackups use.
+	CronSeconds CronDialect = "seconds"
+)
+
+// cronDialects maps dialect names, including the platforms using them, to dialects.
+var cronDialects = map[string]CronDialect{
+	"standard":   CronStandard,
+	"kubernetes": CronStandard,
+	"seconds":    CronSeconds,
+	"cnpg":       CronSeconds,
+}
+
+// cronMacros are the schedules @-macros stand for, in the standard dialect.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes one field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // Names of the values from min, e.g. "jan" for 1
+}
+
+var (
+	cronSecond  = cronField{name: "second", min: 0, max: 59}
+	cronMinute  = cronField{name: "minute", min: 0, max: 59}
+	cronHour    = cronField{name: "hour", min: 0, max: 23}
+	cronDay     = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth   = cronField{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	cronWeekday = cronField{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// cronSearchYears bounds the search for the next run of a schedule.
+const cronSearchYears = 5
+
+// CronSchedule is a valid cron expression of some dialect.
+type CronSchedule struct {
+	value   string
+	dialect CronDialect
+	// Bit sets of the values each field matches: second, minute, hour, day of
+	// month, month and day of week (Sunday is 0).
+	second, minute, hour, day, month, weekday uint64
+	// Whether day of month or day of week is "*" or "?": a day then has to match
+	// both, rather than either as when both are restricted.
+	anyDay, anyWeekday bool
+}
+
+// NewCronSchedule parses a standard five-field cron expression.
+func NewCronSchedule(s string) (CronSchedule, error) {
+	return ParseCronSchedule(s, CronStandard)
+}
+
+// ParseCronSchedule parses a cron expression of the given dialect, checking that
+// every value is in range and that the schedule ever runs.
+func ParseCronSchedule(s string, dialect CronDialect) (CronSchedule, error) {
+	expr := strings.TrimSpace(s)
+	if expr == "" {
+		return CronSchedule{}, fmt.Errorf("cron schedule cannot be empty")
+	}
+	fields := strings.Fields(expr)
+	if strings.HasPrefix(expr, "@") {
+		macro, ok := cronMacros[strings.ToLower(expr)]
+		if !ok {
+			return CronSchedule{}, fmt.Errorf("unknown cron macro %q (use @yearly, @monthly, @weekly, @daily or @hourly)", expr)
+		}
+		fields = strings.Fields(macro)
+		if dialect == CronSeconds {
+			fields = append([]string{"0"}, fields...)
+		}
+	}
+
+	layout := []cronField{cronMinute, cronHour, cronDay, cronMonth, cronWeekday}
+	if dialect == CronSeconds {
+		layout = append([]cronField{cronSecond}, layout...)
+	}
+	if len(fields) != len(layout) {
+		names := make([]string, len(layout))
+		for i, f := range layout {
+			names[i] = f.name
+		}
+		return CronSchedule{}, fmt.Errorf("cron schedule must have exactly %d fields (%s), got %d", len(layout), strings.Join(names, ", "), len(fields))
+	}
+
+	c := CronSchedule{value: s, dialect: dialect, second: 1}
+	sets := []*uint64{&c.minute, &c.hour, &c.day, &c.month, &c.weekday}
+	if dialect == CronSeconds {
+		sets = append([]*uint64{&c.second}, sets...)
+	}
+	for i, field := range layout {
+		set, err := field.parse(fields[i])
+		if err != nil {
+			return CronSchedule{}, err
+		}
+		*sets[i] = set
+	}
+	// Sunday may be written as 7 as well as 0.
+	if c.weekday&(1<<7) != 0 {
+		c.weekday = c.weekday&^(1<<7) | 1
+	}
+	c.anyDay = isAnyValue(fields[len(fields)-3])
+	c.anyWeekday = isAnyValue(fields[len(fields)-1])
+
+	if len(c.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), 1)) == 0 {
+		return CronSchedule{}, fmt.Errorf("cron schedule %q never runs", expr)
+	}
+	return c, nil
+}
+
+func (c CronSchedule) String() string { return c.value }
+
+// Dialect returns the dialect the schedule was parsed in.
+func (c CronSchedule) Dialect() CronDialect { return c.dialect }
+
+// Next returns the next n times after t the schedule runs, in t's location.
+// It returns fewer if the schedule does not run that often within a few years.
+func (c CronSchedule) Next(t time.Time, n int) []time.Time {
+	var runs []time.Time
+	for len(runs) < n {
+		next, ok := c.next(t)
+		if !ok {
+			break
+		}
+		runs = append(runs, next)
+		t = next
+	}
+	return runs
+}
+
+// Upcoming describes the next n runs after t in UTC, e.g.
+// "Sat 18 Oct 2026 00:00, Sun 19 Oct 2026 00:00 (UTC)".
+func (c CronSchedule) Upcoming(t time.Time, n int) string {
+	layout := "Mon 2 Jan 2006 15:04"
+	if c.dialect == CronSeconds {
+		layout = "Mon 2 Jan 2006 15:04:05"
+	}
+	var runs []string
+	for _, run := range c.Next(t.UTC(), n) {
+		runs = append(runs, run.Format(layout))
+	}
+	return strings.Join(runs, ", ") + " (UTC)"
+}
+
+// next returns the first time after t the schedule runs. It moves forward one
+// month, day, hour, minute or second at a time, to the start of that unit, until
+// every field matches.
+func (c CronSchedule) next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.Year() + cronSearchYears
+	for t.Year() <= limit {
+		y, mo, d := t.Date()
+		h, mi, s := t.Clock()
+		switch {
+		case !has(c.month, int(mo)):
+			t = time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		case !has(c.hour, h):
+			t = time.Date(y, mo, d, h+1, 0, 0, 0, loc)
+		case !has(c.minute, mi):
+			t = time.Date(y, mo, d, h, mi+1, 0, 0, loc)
+		case !has(c.second, s):
+			t = t.Add(time.Second)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dayMatches reports whether the schedule runs on t's day. When both day of month
+// and day of week are restricted, either matching suffices, as in Vixie cron.
+func (c CronSchedule) dayMatches(t time.Time) bool {
+	day := has(c.day, t.Day())
+	weekday := has(c.weekday, int(t.Weekday()))
+	if c.anyDay || c.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+func has(set uint64, v int) bool { return set&(1<<uint(v)) != 0 }
+
+func isAnyValue(field string) bool { return field == "*" || field == "?" }
+
+// parse returns the bit set of the values matched by one field: a comma-separated
+// list of "*", "?", values and ranges ("1-5", "mon-fri"), each optionally with a
+// step ("*/15", "0-30/10", "5/10" meaning "5-max/10").
+func (f cronField) parse(text string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(part, "/")
+		lo, hi := f.min, f.max
+		if f.name == cronWeekday.name {
+			hi = 6 // "*" means every day once; 7 is only an alias of Sunday
+		}
+		switch {
+		case rangeText == "*":
+		case rangeText == "?" && (f.name == cronDay.name || f.name == cronWeekday.name):
+		default:
+			from, to, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("%s: range %q ends before it starts", f.name, rangeText)
+				}
+			case hasStep:
+				hi = f.max
+			default:
+				hi = lo
+			}
+		}
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q in %q", f.name, stepText, part)
+			}
+			step = n
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	if bits.OnesCount64(set) == 0 {
+		return 0, fmt.Errorf("%s: %q matches nothing", f.name, text)
+	}
+	return set, nil
+}
+
+// value parses a single value of the field, a number or a name.
+func (f cronField) value(text string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(text, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		if len(f.names) > 0 {
+			return 0, fmt.Errorf("%s: %q is not a number or name (%s)", f.name, text, strings.Join(f.names, ", "))
+		}
+		return 0, fmt.Errorf("%s: %q is not a number", f.name, text)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s: %d is out of range %d-%d", f.name, n, f.min, f.max)
+	}
+	return n, nil
+}
+
+// cronScheduleType builds "cron-schedule", in the dialect given by its argument:
+// "cron-schedule(seconds)" for CNPG, standard by default.
+func cronScheduleType(args ValidationArgs) ([]ValidationRule, error) {
+	if err := args.Expect("dialect"); err != nil {
+		return nil, err
+	}
+	dialect := CronStandard
+	if name, ok := args.Get("dialect", 0); ok {
+		d, known := cronDialects[name]
+		if !known {
+			return nil, fmt.Errorf("unknown cron dialect %q (use standard or seconds)", name)
+		}
+		dialect = d
+	}
+	return []ValidationRule{{Name: "cron-schedule", Check: func(s string) (ValueObject, error) {
+		return ParseCronSchedule(s, dialect)
+	}}}, nil
+}
+
+// upcomingRuns is how many runs of a schedule are shown.
+const upcomingRuns = 3
+
+// CronUpcoming describes the next runs after t of value as a schedule of
+// validationType, such as "cron-schedule(seconds)", or returns "" if the type is
+// not a cron schedule type or value is not a valid schedule of it.
+func CronUpcoming(validationType, value string, t time.Time) string {
+	if ValidationTypeName(validationType) != "cron-schedule" || strings.TrimSpace(value) == "" {
+		return ""
+	}
+	validator, err := NewValidator(validationType)
+	if err != nil {
+		return ""
+	}
+	parsed, err := validator.Parse(value)
+	schedule, ok := parsed.(CronSchedule)
+	if err != nil || !ok {
+		return ""
+	}
+	return schedule.Upcoming(t, upcomingRuns)
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCronSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"every minute", "* * * * *", false},
+		{"midnight daily", "0 0 * * *", false},
+		{"weekdays at 5am", "0 5 * * 1-5", false},
+		{"every 5 minutes", "*/5 * * * *", false},
+		{"complex", "0,30 9-17 * * 1-5", false},
+		{"specific day and time", "15 14 1 * *", false},
+		{"month and weekday names", "0 6 * JAN,jul mon-fri", false},
+		{"sunday as 7", "0 0 * * 7", false},
+		{"step from value", "5/15 * * * *", false},
+		{"range with step", "0-30/10 * * * *", false},
+		{"any day", "0 0 ? * 1", false},
+		{"macro", "@daily", false},
+		{"macro any case", "@Weekly", false},
+		{"empty", "", true},
+		{"whitespace only", "   ", true},
+		{"too few fields", "* * *", true},
+		{"too many fields", "* * * * * *", true},
+		{"invalid characters", "0 0 * * abc", true},
+		{"not a cron", "not a cron", true},
+		{"single field", "0", true},
+		{"minute out of range", "75 * * * *", true},
+		{"hour out of range", "0 24 * * *", true},
+		{"day of month zero", "0 0 0 * *", true},
+		{"month out of range", "0 0 1 13 *", true},
+		{"weekday out of range", "0 0 * * 8", true},
+		{"reversed range", "0 0 * * 5-1", true},
+		{"zero step", "*/0 * * * *", true},
+		{"unknown macro", "@fortnightly", true},
+		{"never runs", "0 0 30 feb *", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewCronSchedule(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCronSchedule(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && v.String() != tt.input {
+				t.Errorf("NewCronSchedule(%q).String() = %q, want %q", tt.input, v.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleSeconds(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"midnight daily", "0 0 0 * * *", ""},
+		{"every 30 seconds", "*/30 * * * * *", ""},
+		{"macro", "@daily", ""},
+		{"five fields", "0 0 * * *", "must have exactly 6 fields"},
+		{"second out of range", "60 0 0 * * *", "second: 60 is out of range 0-59"},
+		{"minute out of range", "0 75 0 * * *", "minute: 75 is out of range 0-59"},
+		{"unknown month", "0 0 0 * foo *", `month: "foo" is not a number or name`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := ParseCronSchedule(tt.input, CronSeconds)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseCronSchedule(%q) returned error: %v", tt.input, err)
+				}
+				if v.Dialect() != CronSeconds {
+					t.Errorf("Dialect() = %q, want %q", v.Dialect(), CronSeconds)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCronSchedule(%q) error = %v, want it to contain %q", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// A Saturday.
+	after := time.Date(2026, 10, 17, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		name    string
+		input   string
+		dialect CronDialect
+		want    []string
+	}{
+		{"daily", "0 0 * * *", CronStandard, []string{"2026-10-18T00:00:00Z", "2026-10-19T00:00:00Z"}},
+		{"every 15 minutes", "*/15 * * * *", CronStandard, []string{"2026-10-17T10:45:00Z", "2026-10-17T11:00:00Z"}},
+		{"weekdays", "0 9 * * mon-fri", CronStandard, []string{"2026-10-19T09:00:00Z", "2026-10-20T09:00:00Z"}},
+		{"sunday as 7", "0 0 * * 7", CronStandard, []string{"2026-10-18T00:00:00Z", "2026-10-25T00:00:00Z"}},
+		{"day of month or weekday", "0 0 1 * sun", CronStandard, []string{"2026-10-18T00:00:00Z", "2026-10-25T00:00:00Z", "2026-11-01T00:00:00Z"}},
+		{"leap day", "0 0 29 feb *", CronStandard, []string{"2028-02-29T00:00:00Z"}},
+		{"monthly macro", "@monthly", CronStandard, []string{"2026-11-01T00:00:00Z"}},
+		{"seconds", "*/20 * * * * *", CronSeconds, []string{"2026-10-17T10:30:20Z", "2026-10-17T10:30:40Z"}},
+		{"seconds daily", "30 0 2 * * *", CronSeconds, []string{"2026-10-18T02:00:30Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCronSchedule(tt.input, tt.dialect)
+			if err != nil {
+				t.Fatalf("ParseCronSchedule(%q) returned error: %v", tt.input, err)
+			}
+			var got []string
+			for _, run := range c.Next(after, len(tt.want)) {
+				got = append(got, run.Format(time.RFC3339))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronUpcoming(t *testing.T) {
+	after := time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		validationType string
+		value          string
+		want           string
+	}{
+		{"standard", "cron-schedule", "0 0 * * *", "Sun 18 Oct 2026 00:00, Mon 19 Oct 2026 00:00, Tue 20 Oct 2026 00:00 (UTC)"},
+		{"seconds", "cron-schedule(seconds)", "0 0 0 * * *", "Sun 18 Oct 2026 00:00:00, Mon 19 Oct 2026 00:00:00, Tue 20 Oct 2026 00:00:00 (UTC)"},
+		{"invalid schedule", "cron-schedule", "0 0 0 * * *", ""},
+		{"empty", "cron-schedule", "", ""},
+		{"other type", "string", "0 0 * * *", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CronUpcoming(tt.validationType, tt.value, after); got != tt.want {
+				t.Errorf("CronUpcoming(%q, %q) = %q, want %q", tt.validationType, tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError reports a value given for a field that was rejected. Rule names
+// the validation rule or cross-field rule that rejected it, if there is one, and
+// Message says why without the field or rule.
+type ValidationError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+// NewValidationError returns the error for value of field being rejected with err,
+// taking the rule from a RuleError err wraps.
+func NewValidationError(field, value string, err error) *ValidationError {
+	e := &ValidationError{Field: field, Value: value, Message: err.Error(), Err: err}
+	var ruleErr *RuleError
+	if errors.As(err, &ruleErr) {
+		e.Rule = ruleErr.Rule
+		if ruleErr == err {
+			e.Message = ruleErr.Err.Error()
+		}
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid value for %q: %v", e.Field, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// ValidationErrors reports all values that were rejected, in the order their
+// fields are asked.
+type ValidationErrors []*ValidationError
+
+// Error returns a single error as is and lists several, one per line.
+func (errs ValidationErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invalid values:", len(errs))
+	for _, e := range errs {
+		fmt.Fprintf(&b, "\n  %s: %s", e.Field, e.Message)
+		if e.Rule != "" {
+			fmt.Fprintf(&b, " (%s)", e.Rule)
+		}
+		fmt.Fprintf(&b, "\n    got %q", e.Value)
+	}
+	return b.String()
+}
+
+func (errs ValidationErrors) Unwrap() []error {
+	wrapped := make([]error, len(errs))
+	for i, e := range errs {
+		wrapped[i] = e
+	}
+	return wrapped
+}
+
+// Err returns errs as an error, or nil if there are none.
+func (errs ValidationErrors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewValidationError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantRule    string
+		wantMessage string
+	}{
+		{"rule", &RuleError{Rule: "max=9", Err: errors.New("must be at most 9")}, "max=9", "must be at most 9"},
+		{"wrapped rule", fmt.Errorf("entry 2: %w", &RuleError{Rule: "port", Err: errors.New("must be a number")}), "port", "entry 2: port: must be a number"},
+		{"no rule", errors.New("must not be empty"), "", "must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewValidationError("instances", "12", tt.err)
+			if e.Rule != tt.wantRule || e.Message != tt.wantMessage {
+				t.Errorf("NewValidationError() = rule %q, message %q, want %q, %q", e.Rule, e.Message, tt.wantRule, tt.wantMessage)
+			}
+			if want := `invalid value for "instances": ` + tt.err.Error(); e.Error() != want {
+				t.Errorf("Error() = %q, want %q", e.Error(), want)
+			}
+			if !errors.Is(e, tt.err) {
+				t.Error("expected the error to wrap the cause")
+			}
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	name := NewValidationError("name", "Main", &RuleError{Rule: "dns-name", Err: errors.New("must be lowercase")})
+	instances := NewValidationError("instances", "12", &RuleError{Rule: "max=9", Err: errors.New("must be at most 9")})
+
+	if err := (ValidationErrors{}).Err(); err != nil {
+		t.Errorf("Err() of no errors = %v, want nil", err)
+	}
+	if got, want := (ValidationErrors{name}).Error(), name.Error(); got != want {
+		t.Errorf("Error() of one error = %q, want %q", got, want)
+	}
+
+	err := ValidationErrors{name, instances}.Err()
+	want := "2 invalid values:\n" +
+		"  name: must be lowercase (dns-name)\n" +
+		"    got \"Main\"\n" +
+		"  instances: must be at most 9 (max=9)\n" +
+		"    got \"12\""
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	var got *ValidationError
+	if !errors.As(err, &got) || got != name {
+		t.Errorf("expected errors.As to find the first error, got %v", got)
+	}
+}
@@ -8,17 +8,38 @@ const (
 	FieldAutoList                       // Pulled from k8s
 	FieldTemplateGroup                  // Pick from sub-template group
 	FieldStaticList                     // Pick from static predefined list
+	FieldDerived                        // Computed from other values, never prompted
+	FieldRepeatable                     // User-provided list of validated entries
 )
 
 // FieldDefinition is extracted from a template during the first pass.
 type FieldDefinition struct {
 	Name           string
 	Type           FieldType
-	ValidationType string // For input: "dns-name", "integer", "string", etc.
-	Source         string // For autoList: "namespace", "cnpg-clusters"; for templateGroup/staticList: group/list name
+	ValidationType string   // For input: "dns-name", "integer", "string", etc.
+	Source         string   // For autoList: "namespace", "cnpg-clusters"; for templateGroup/staticList: group/list name
+	Default        string   // Pre-filled value used when neither a flag nor an answer is given
+	Label          string   // Human-readable prompt title; falls back to Name
+	Description    string   // Help text shown in the wizard and flag usage
+	Placeholder    string   // Example value shown in empty inputs
+	Optional       bool     // May be left empty
+	Multiple       bool     // staticList/templateGroup: several items may be selected
+	Condition      string   // Template expression that must hold for the field to be asked; empty means always
+	DependsOn      []string // Fields referenced by Condition
+	Expression     string   // For derived: template expression computing the value
+	Page           string   // Name of the wizard page the field is asked on; empty for the default page
+	Namespace      string   // For cnpg-clusters autoList: namespace field clusters are listed from; empty for the closest one
 	Order          int
 }
 
+// Title returns the human-readable label for the field, falling back to its name.
+func (f FieldDefinition) Title() string {
+	if f.Label != "" {
+		return f.Label
+	}
+	return f.Name
+}
+
 // FieldValue holds a collected value for a field.
 type FieldValue struct {
 	Definition FieldDefinition
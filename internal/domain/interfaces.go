@@ -5,6 +5,9 @@ type KubeClient interface {
 	ListContexts() ([]string, error)
 	ListNamespaces(context string) ([]string, error)
 	ListCNPGClusters(context string, namespace string) ([]string, error)
+	// Lookup returns an object as unstructured content, or the list of all objects of
+	// the kind when name is empty. A missing object yields nil without an error.
+	Lookup(context, apiVersion, kind, namespace, name string) (map[string]interface{}, error)
 }
 
 // TemplateRegistry indexes and retrieves templates and sub-templates.
@@ -12,11 +15,32 @@ type TemplateRegistry interface {
 	GetTemplate(name string) (*TemplateMeta, error)
 	GetSubTemplates(group string) ([]SubTemplateMeta, error)
 	GetStaticList(name string) (*StaticListMeta, error)
+	GetPartial(name string) (*PartialMeta, error)
 	ListTemplates() []TemplateMeta
 	ListTemplatesByCommandPrefix(prefix string) []TemplateMeta
+	Dir() string // Directory the templates were loaded from
 }
 
 // ManifestWriter writes rendered manifest content to files.
 type ManifestWriter interface {
 	Write(content string, outputDir string, filename string) (string, error)
 }
+
+// ConditionEvaluator decides whether a conditional field applies given the values collected so far.
+type ConditionEvaluator interface {
+	EvalCondition(condition string, values map[string]string) (bool, error)
+}
+
+// RuleChecker checks cross-field rules against the values collected so far.
+type RuleChecker interface {
+	CheckRule(rule Rule, values map[string]string) error
+	// CheckRules checks every rule against the answers to fields whose condition holds.
+	// When complete, a rule needing a field left unanswered is reported rather than skipped.
+	CheckRules(fields []FieldDefinition, rules []Rule, values map[string]string, complete bool) error
+}
+
+// Evaluator evaluates the conditions and rules of a template.
+type Evaluator interface {
+	ConditionEvaluator
+	RuleChecker
+}
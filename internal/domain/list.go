@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListSeparator separates the entries of a repeatable field within a single value.
+const ListSeparator = "\n"
+
+// SplitList returns the non-blank entries of a repeatable field value.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ListSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// JoinList encodes entries as a single repeatable field value.
+func JoinList(items []string) string {
+	return strings.Join(items, ListSeparator)
+}
+
+// ObjectField is one sub-field of an object-valued repeatable field.
+type ObjectField struct {
+	Name           string
+	ValidationType string
+}
+
+// ParseObjectSchema parses an object schema such as "name:dns-name,port:integer(min=1)".
+// It returns false if spec is a plain validation type rather than a schema.
+func ParseObjectSchema(spec string) ([]ObjectField, bool) {
+	head, _, _ := strings.Cut(spec, "(")
+	if !strings.Contains(head, ":") {
+		return nil, false
+	}
+	parts, err := splitTopLevel(spec, ',')
+	if err != nil {
+		parts = strings.Split(spec, ",")
+	}
+	var schema []ObjectField
+	for _, part := range parts {
+		name, vt, _ := strings.Cut(part, ":")
+		schema = append(schema, ObjectField{Name: strings.TrimSpace(name), ValidationType: strings.TrimSpace(vt)})
+	}
+	return schema, true
+}
+
+// ParseObjectEntry splits an object entry such as "name=alice,port=5432" into its sub-field values.
+// A value containing commas or parentheses is written in double quotes, as in
+// `name=alice,note="a, b"`.
+func ParseObjectEntry(entry string) (map[string]string, error) {
+	parts, err := splitTopLevel(entry, ',')
+	if err != nil {
+		return nil, fmt.Errorf("%w in %q, quote values containing commas or parentheses", err, entry)
+	}
+	result := make(map[string]string)
+	for _, part := range parts {
+		key, value, _ := strings.Cut(part, "=")
+		v, err := unquoteArg(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		result[strings.TrimSpace(key)] = v
+	}
+	return result, nil
+}
+
+// ValidateListEntry validates one entry of a repeatable field, either against a plain
+// validation type or, for an object schema, each of its sub-fields.
+func ValidateListEntry(validationType string, entry string) error {
+	schema, isObject := ParseObjectSchema(validationType)
+	if !isObject {
+		_, err := ParseValue(validationType, entry)
+		return err
+	}
+
+	values, err := ParseObjectEntry(entry)
+	if err != nil {
+		return err
+	}
+	for _, field := range schema {
+		v, ok := values[field.Name]
+		if !ok {
+			return fmt.Errorf("missing %q in %q", field.Name, entry)
+		}
+		if _, err := ParseValue(field.ValidationType, v); err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+		delete(values, field.Name)
+	}
+	for key := range values {
+		return fmt.Errorf("unknown key %q in %q", key, entry)
+	}
+	return nil
+}
+
+// ValidateList validates every entry of a repeatable field value.
+func ValidateList(validationType string, value string) error {
+	for i, entry := range SplitList(value) {
+		if err := ValidateListEntry(validationType, entry); err != nil {
+			return fmt.Errorf("entry %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
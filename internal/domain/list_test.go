@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"single", "app", []string{"app"}},
+		{"multiple", "app\nreadonly", []string{"app", "readonly"}},
+		{"blank lines skipped", "app\n\n  \nreadonly\n", []string{"app", "readonly"}},
+		{"whitespace trimmed", "  app  ", []string{"app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitList(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SplitList(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("SplitList(%q)[%d] = %q, want %q", tt.input, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateListEntry(t *testing.T) {
+	tests := []struct {
+		name           string
+		validationType string
+		entry          string
+		wantErr        bool
+	}{
+		{"valid scalar", "dns-name", "app", false},
+		{"invalid scalar", "dns-name", "App", true},
+		{"valid object", "name:dns-name,port:port", "name=app,port=5432", false},
+		{"valid object with spaces", "name:dns-name, port:port", "name=app, port=5432", false},
+		{"object missing key", "name:dns-name,port:port", "name=app", true},
+		{"object invalid value", "name:dns-name,port:port", "name=app,port=0", true},
+		{"object unknown key", "name:dns-name", "name=app,extra=1", true},
+		{"object with arguments", "name:dns-name,port:integer(min=1024,max=65535)", "name=app,port=5432", false},
+		{"object argument rule", "name:dns-name,port:integer(min=1024,max=65535)", "name=app,port=80", true},
+		{"scalar with quoted colon", `regex("^a:b")`, "a:b", false},
+		{"object quoted value with comma", "name:dns-name,note:string", `name=app,note="read, write"`, false},
+		{"object unterminated quote", "name:dns-name,note:string", `name=app,note="read, write`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateListEntry(tt.validationType, tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateListEntry(%q, %q) error = %v, wantErr %v", tt.validationType, tt.entry, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseObjectEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    map[string]string
+		wantErr string
+	}{
+		{"plain", "name=app, port=5432", map[string]string{"name": "app", "port": "5432"}, ""},
+		{"quoted comma", `name=app,note="read, write"`, map[string]string{"name": "app", "note": "read, write"}, ""},
+		{"quoted escapes", `note="say \"hi\", (ok)"`, map[string]string{"note": `say "hi", (ok)`}, ""},
+		{"parentheses", "note=f(a,b)", map[string]string{"note": "f(a,b)"}, ""},
+		{"unterminated quote", `note="a,b`, nil, "unterminated quote"},
+		{"unbalanced parenthesis", "note=a(b", nil, "quote values containing commas or parentheses"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseObjectEntry(tt.entry)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseObjectEntry(%q) error = %v, want %q", tt.entry, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseObjectEntry(%q) error: %v", tt.entry, err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("ParseObjectEntry(%q) = %v, want %v", tt.entry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateList(t *testing.T) {
+	if err := ValidateList("dns-name", "app\nreadonly"); err != nil {
+		t.Errorf("ValidateList() unexpected error: %v", err)
+	}
+	if err := ValidateList("dns-name", "app\nRead_Only"); err == nil {
+		t.Error("ValidateList() expected error for invalid entry")
+	}
+}
@@ -7,6 +7,68 @@ type TemplateMeta struct {
 	Command     string // e.g., "cluster cnpg"
 	Description string
 	FilePath    string
+	Bundle      bool   // Renders several YAML documents, written to a file each by default
+	Extends     string // Name of the base template whose blocks this one overrides; empty if none
+
+	Version            string               // Template version, e.g. "1.2.0"
+	Authors            []string             // Template maintainers
+	Tags               []string             // Free-form keywords, e.g. "postgres"
+	MinInscribeVersion string               // Oldest inscribe release able to render the template
+	OutputFilename     string               // Default output filename, a template such as "{{ .name }}-cluster.yaml"
+	RequiresCRDs       []string             // CRDs the rendered manifests need, e.g. "clusters.postgresql.cnpg.io"
+	Fields             map[string]FieldMeta // Field metadata declared in the header, by field name
+	Pages              []Page               // Wizard pages, in the order they are shown
+	Rules              []Rule               // Cross-field rules declared in the header, not including a base template's
+}
+
+// Rule is a cross-field constraint declared in a template header, such as
+// "limits-memory >= requests-memory" or "name.startsWith(namespace)". It is checked
+// once the fields it needs have been answered.
+type Rule struct {
+	Expression string
+	Message    string   // Reported when the rule does not hold; describes the values if empty
+	Fields     []string // Fields a failure is reported on; the one referred to that is asked last if not declared
+	Refs       []string // Fields the expression refers to
+	Compiled   RuleExpr // The expression compiled when the template is loaded
+}
+
+// RuleExpr is a compiled rule expression.
+type RuleExpr interface {
+	// Check reports an error if the rule does not hold for values, or cannot be
+	// checked because a field it needs is not answered.
+	Check(values map[string]string) error
+}
+
+// Page is a titled wizard page grouping the fields assigned to it with the page option.
+type Page struct {
+	Name        string // Referenced by the page option, e.g. "storage"
+	Title       string // Shown above the page's fields; the name if empty
+	Description string
+}
+
+// DisplayTitle returns the title shown for the page: its title, or its name if unset.
+func (p Page) DisplayTitle() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return p.Name
+}
+
+// FieldMeta is field metadata declared in a template header instead of as options
+// of the field function. Options given to the field function take precedence.
+type FieldMeta struct {
+	Label       string
+	Help        string
+	Placeholder string
+	Default     string
+	Optional    bool
+	Page        string
+}
+
+// Document is one YAML document of a rendered template.
+type Document struct {
+	Filename string // Target file declared with outputFile; empty if none
+	Content  string
 }
 
 // SubTemplateMeta describes a sub-template fragment.
@@ -17,9 +79,18 @@ type SubTemplateMeta struct {
 	FilePath    string
 }
 
+// PartialMeta describes a reusable fragment included by name from templates and sub-templates.
+type PartialMeta struct {
+	Name     string // e.g., "std-metadata"
+	Content  string // Raw template content (without header comment)
+	FilePath string
+}
+
 // StaticListMeta describes a static list of predefined values.
 type StaticListMeta struct {
-	Name     string
-	Items    []string
-	FilePath string
+	Name           string
+	Items          []string
+	ItemConditions []string // Per-item condition from a "# show=" rule, parallel to Items; empty means always offered
+	DependsOn      []string // Fields referenced by ItemConditions
+	FilePath       string
 }
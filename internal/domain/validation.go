@@ -0,0 +1,464 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ValidationRule is one named check of a validation type, such as "integer" or
+// "max=9". Check returns the value object for a valid value; rules that only
+// constrain a value already parsed by an earlier rule may return nil.
+type ValidationRule struct {
+	Name  string
+	Check func(value string) (ValueObject, error)
+}
+
+// ValidationTypeFactory builds the rules of a validation type from the arguments it
+// is declared with, e.g. min and max for "integer(min=1,max=9)".
+type ValidationTypeFactory func(args ValidationArgs) ([]ValidationRule, error)
+
+// RuleError reports the rule of a validation type that rejected a value.
+type RuleError struct {
+	Rule string
+	Err  error
+}
+
+func (e *RuleError) Error() string { return fmt.Sprintf("%s: %v", e.Rule, e.Err) }
+
+func (e *RuleError) Unwrap() error { return e.Err }
+
+// validationTypes holds the registered validation types by name.
+var validationTypes = map[string]ValidationTypeFactory{
+	"dns-name":      simpleType("dns-name", NewDNSName),
+	"dns-subdomain": simpleType("dns-subdomain", NewDNSSubdomain),
+	"integer":       integerType,
+	"string":        simpleType("string", NewNonEmptyString),
+	"port":          simpleType("port", NewPort),
+	"memory":        quantityType("memory", NewMemory),
+	"cpu":           quantityType("cpu", NewCPU),
+	"storage":       quantityType("storage", NewStorageSize),
+	"cron-schedule": cronScheduleType,
+	"filename":      simpleType("filename", NewFilename),
+	"path":          simpleType("path", NewPath),
+	"image":         simpleType("image", NewImageReference),
+	"label-key":     simpleType("label-key", NewLabelKey),
+	"label-value":   simpleType("label-value", NewLabelValue),
+	"url":           simpleType("url", NewURL),
+	"duration":      simpleType("duration", NewDuration),
+	"semver":        simpleType("semver", NewSemVer),
+	"boolean":       simpleType("boolean", NewBoolean),
+	"ip":            simpleType("ip", NewIPAddress),
+	"cidr":          simpleType("cidr", NewCIDR),
+	"email":         simpleType("email", NewEmail),
+	"regex":         regexType,
+	"enum":          enumType,
+	"length":        lengthType,
+}
+
+// RegisterValidationType makes a validation type available to templates under name,
+// replacing any type registered before. Types are meant to be registered from an
+// init function, before any value is validated.
+func RegisterValidationType(name string, factory ValidationTypeFactory) {
+	validationTypes[name] = factory
+}
+
+// Validator checks values against a validation type such as "dns-name" or
+// "integer(min=1,max=9)".
+type Validator struct {
+	Name     string // Registered type name, e.g. "integer"
+	Optional bool   // Empty values are accepted without running the rules
+	rules    []ValidationRule
+}
+
+// NewValidator parses a validation type declaration and builds its rules.
+// Arguments go in parentheses after the type name, positional or as key=value, and
+// may be double-quoted to contain commas or parentheses. The "optional" argument is
+// accepted by every type.
+func NewValidator(spec string) (*Validator, error) {
+	name, rawArgs, err := splitValidationType(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation type %q: %w", spec, err)
+	}
+	factory, ok := validationTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown validation type: %q", name)
+	}
+	args, optional, err := parseValidationArgs(rawArgs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation type %q: %w", spec, err)
+	}
+	rules, err := factory(args)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation type %q: %w", spec, err)
+	}
+	return &Validator{Name: name, Optional: optional, rules: rules}, nil
+}
+
+// Parse checks value against every rule, returning the value object of the first
+// rule producing one. An empty value of an optional type is accepted as nil.
+func (v *Validator) Parse(value string) (ValueObject, error) {
+	if v.Optional && value == "" {
+		return nil, nil
+	}
+	var obj ValueObject
+	for _, rule := range v.rules {
+		o, err := rule.Check(value)
+		if err != nil {
+			return nil, &RuleError{Rule: rule.Name, Err: err}
+		}
+		if obj == nil {
+			obj = o
+		}
+	}
+	return obj, nil
+}
+
+// ValidationTypeName returns the type name of a validation type declaration,
+// without its arguments: "integer" for "integer(min=1)".
+func ValidationTypeName(spec string) string {
+	name, _, _ := strings.Cut(spec, "(")
+	return strings.TrimSpace(name)
+}
+
+// ValidationArgs are the arguments of a validation type declaration.
+type ValidationArgs struct {
+	Positional []string
+	Named      map[string]string
+}
+
+// Get returns the argument given by name or, failing that, at position.
+func (a ValidationArgs) Get(name string, position int) (string, bool) {
+	if v, ok := a.Named[name]; ok {
+		return v, true
+	}
+	if position < len(a.Positional) {
+		return a.Positional[position], true
+	}
+	return "", false
+}
+
+// Expect reports an error unless the arguments are the given parameters, in order
+// when positional, each given at most once.
+func (a ValidationArgs) Expect(params ...string) error {
+	if len(a.Positional) > len(params) {
+		if len(params) == 0 {
+			return fmt.Errorf("takes no arguments")
+		}
+		return fmt.Errorf("takes at most %d arguments (%s)", len(params), strings.Join(params, ", "))
+	}
+	for name := range a.Named {
+		i := slices.Index(params, name)
+		if i < 0 {
+			return fmt.Errorf("unknown argument %q", name)
+		}
+		if i < len(a.Positional) {
+			return fmt.Errorf("argument %q given twice", name)
+		}
+	}
+	return nil
+}
+
+// Int returns the integer argument given by name or at position.
+func (a ValidationArgs) Int(name string, position int) (int, bool, error) {
+	s, ok := a.Get(name, position)
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false, fmt.Errorf("argument %q must be an integer, got %q", name, s)
+	}
+	return n, true, nil
+}
+
+// splitValidationType splits "name(args)" into its name and the text of its arguments.
+func splitValidationType(spec string) (string, string, error) {
+	spec = strings.TrimSpace(spec)
+	name, rest, hasArgs := strings.Cut(spec, "(")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", fmt.Errorf("missing type name")
+	}
+	if !hasArgs {
+		return name, "", nil
+	}
+	if !strings.HasSuffix(rest, ")") {
+		return "", "", fmt.Errorf("missing closing parenthesis")
+	}
+	return name, strings.TrimSuffix(rest, ")"), nil
+}
+
+// parseValidationArgs parses comma-separated arguments, reporting the "optional"
+// argument separately.
+func parseValidationArgs(raw string) (ValidationArgs, bool, error) {
+	args := ValidationArgs{Named: make(map[string]string)}
+	optional := false
+	if strings.TrimSpace(raw) == "" {
+		return args, false, nil
+	}
+	parts, err := splitTopLevel(raw, ',')
+	if err != nil {
+		return args, false, err
+	}
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "optional" {
+			optional = true
+			continue
+		}
+		if !strings.HasPrefix(part, `"`) {
+			if key, value, ok := strings.Cut(part, "="); ok {
+				key = strings.TrimSpace(key)
+				if _, dup := args.Named[key]; dup {
+					return args, false, fmt.Errorf("argument %q given twice", key)
+				}
+				v, err := unquoteArg(strings.TrimSpace(value))
+				if err != nil {
+					return args, false, err
+				}
+				args.Named[key] = v
+				continue
+			}
+		}
+		v, err := unquoteArg(part)
+		if err != nil {
+			return args, false, err
+		}
+		args.Positional = append(args.Positional, v)
+	}
+	return args, optional, nil
+}
+
+// unquoteArg returns an argument without its double quotes, if it has them.
+func unquoteArg(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	v, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted argument %s", s)
+	}
+	return v, nil
+}
+
+// splitTopLevel splits s at sep, except inside double quotes or parentheses.
+func splitTopLevel(s string, sep rune) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	inQuote, escaped := false, false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuote && r == '\\':
+			escaped = true
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return append(parts, s[start:]), nil
+}
+
+// simpleType returns the factory of a type without arguments, checked by parse.
+func simpleType[T ValueObject](name string, parse func(string) (T, error)) ValidationTypeFactory {
+	return func(args ValidationArgs) ([]ValidationRule, error) {
+		if err := args.Expect(); err != nil {
+			return nil, err
+		}
+		return []ValidationRule{{Name: name, Check: func(s string) (ValueObject, error) {
+			return parse(s)
+		}}}, nil
+	}
+}
+
+// quantityType returns the factory of a resource quantity type, optionally bounded
+// by min and max quantities: "memory(min=256Mi,max=64Gi)".
+func quantityType[T QuantityValue](name string, parse func(string) (T, error)) ValidationTypeFactory {
+	return func(args ValidationArgs) ([]ValidationRule, error) {
+		if err := args.Expect("min", "max"); err != nil {
+			return nil, err
+		}
+		rules := []ValidationRule{{Name: name, Check: func(s string) (ValueObject, error) {
+			return parse(s)
+		}}}
+		var bounds []resource.Quantity
+		for i, param := range []string{"min", "max"} {
+			arg, ok := args.Get(param, i)
+			if !ok {
+				continue
+			}
+			limit, err := parse(arg)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q: %w", param, err)
+			}
+			bound := limit.Quantity()
+			bounds = append(bounds, bound)
+			rules = append(rules, ValidationRule{
+				Name: fmt.Sprintf("%s=%s", param, limit),
+				Check: func(s string) (ValueObject, error) {
+					v, err := parse(s)
+					if err != nil {
+						return nil, err
+					}
+					q := v.Quantity()
+					if param == "min" && q.Cmp(bound) < 0 {
+						return nil, fmt.Errorf("must be at least %s", limit)
+					}
+					if param == "max" && q.Cmp(bound) > 0 {
+						return nil, fmt.Errorf("must be at most %s", limit)
+					}
+					return nil, nil
+				},
+			})
+		}
+		if len(bounds) == 2 && bounds[0].Cmp(bounds[1]) > 0 {
+			return nil, fmt.Errorf("min %s is greater than max %s", bounds[0].String(), bounds[1].String())
+		}
+		return rules, nil
+	}
+}
+
+// integerType builds "integer", optionally bounded by min and max. Without a
+// minimum the integer must be non-negative.
+func integerType(args ValidationArgs) ([]ValidationRule, error) {
+	if err := args.Expect("min", "max"); err != nil {
+		return nil, err
+	}
+	lo, hasMin, err := args.Int("min", 0)
+	if err != nil {
+		return nil, err
+	}
+	hi, hasMax, err := args.Int("max", 1)
+	if err != nil {
+		return nil, err
+	}
+	if hasMin && hasMax && lo > hi {
+		return nil, fmt.Errorf("min %d is greater than max %d", lo, hi)
+	}
+
+	if !hasMin {
+		rules := []ValidationRule{{Name: "integer", Check: func(s string) (ValueObject, error) {
+			return NewInteger(s)
+		}}}
+		return appendBound(rules, hasMax, "max", hi, atoi, "must be at most %d"), nil
+	}
+	rules := []ValidationRule{{Name: "integer", Check: func(s string) (ValueObject, error) {
+		if _, err := strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("must be a valid integer")
+		}
+		return Integer{value: s}, nil
+	}}}
+	rules = appendBound(rules, true, "min", lo, atoi, "must be at least %d")
+	return appendBound(rules, hasMax, "max", hi, atoi, "must be at most %d"), nil
+}
+
+// lengthType builds "length(min,max)": a string of min to max characters.
+func lengthType(args ValidationArgs) ([]ValidationRule, error) {
+	if err := args.Expect("min", "max"); err != nil {
+		return nil, err
+	}
+	lo, hasMin, err := args.Int("min", 0)
+	if err != nil {
+		return nil, err
+	}
+	hi, hasMax, err := args.Int("max", 1)
+	if err != nil {
+		return nil, err
+	}
+	if !hasMin && !hasMax {
+		return nil, fmt.Errorf("needs a min or max length")
+	}
+	if hasMin && hasMax && lo > hi {
+		return nil, fmt.Errorf("min %d is greater than max %d", lo, hi)
+	}
+	runes := func(s string) (int, error) { return utf8.RuneCountInString(s), nil }
+	rules := []ValidationRule{{Name: "string", Check: func(s string) (ValueObject, error) {
+		return NonEmptyString{value: s}, nil
+	}}}
+	rules = appendBound(rules, hasMin, "min", lo, runes, "must be at least %d characters")
+	return appendBound(rules, hasMax, "max", hi, runes, "must be at most %d characters"), nil
+}
+
+// regexType builds "regex(pattern)": a non-empty string matching pattern.
+func regexType(args ValidationArgs) ([]ValidationRule, error) {
+	if err := args.Expect("pattern"); err != nil {
+		return nil, err
+	}
+	pattern, ok := args.Get("pattern", 0)
+	if !ok {
+		return nil, fmt.Errorf("needs a pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return []ValidationRule{{Name: "regex", Check: func(s string) (ValueObject, error) {
+		if s == "" {
+			return nil, fmt.Errorf("value cannot be empty")
+		}
+		if !re.MatchString(s) {
+			return nil, fmt.Errorf("must match %s", pattern)
+		}
+		return NonEmptyString{value: s}, nil
+	}}}, nil
+}
+
+// enumType builds "enum(a,b,c)": one of the given values.
+func enumType(args ValidationArgs) ([]ValidationRule, error) {
+	if len(args.Named) > 0 {
+		return nil, fmt.Errorf("takes only values")
+	}
+	if len(args.Positional) == 0 {
+		return nil, fmt.Errorf("needs at least one value")
+	}
+	values := args.Positional
+	return []ValidationRule{{Name: "enum", Check: func(s string) (ValueObject, error) {
+		if !slices.Contains(values, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+		}
+		return NonEmptyString{value: s}, nil
+	}}}, nil
+}
+
+func atoi(s string) (int, error) { return strconv.Atoi(s) }
+
+// appendBound adds a rule named "name=limit" comparing measure(value) to limit:
+// at least limit for "min", at most limit otherwise.
+func appendBound(rules []ValidationRule, has bool, name string, limit int, measure func(string) (int, error), format string) []ValidationRule {
+	if !has {
+		return rules
+	}
+	return append(rules, ValidationRule{
+		Name: fmt.Sprintf("%s=%d", name, limit),
+		Check: func(s string) (ValueObject, error) {
+			n, err := measure(s)
+			if err != nil {
+				return nil, err
+			}
+			if (name == "min" && n < limit) || (name != "min" && n > limit) {
+				return nil, fmt.Errorf(format, limit)
+			}
+			return nil, nil
+		},
+	})
+}
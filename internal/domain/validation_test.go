@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewValidator(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         string
+		wantName     string
+		wantOptional bool
+		wantErr      string
+	}{
+		{"plain type", "dns-name", "dns-name", false, ""},
+		{"named arguments", "integer(min=1,max=9)", "integer", false, ""},
+		{"positional arguments", "length(3, 20)", "length", false, ""},
+		{"quoted argument", `regex("^pg-(a|b),c$")`, "regex", false, ""},
+		{"optional", "string(optional)", "string", true, ""},
+		{"optional with arguments", "integer(max=9, optional)", "integer", true, ""},
+		{"unknown type", "colour", "", false, `unknown validation type: "colour"`},
+		{"unknown argument", "integer(least=1)", "", false, `unknown argument "least"`},
+		{"argument given twice", "length(3, min=4)", "", false, `argument "min" given twice`},
+		{"too many arguments", "dns-name(3)", "", false, "takes no arguments"},
+		{"not an integer", "integer(min=one)", "", false, `argument "min" must be an integer`},
+		{"min above max", "integer(min=9,max=1)", "", false, "min 9 is greater than max 1"},
+		{"missing parenthesis", "integer(min=1", "", false, "missing closing parenthesis"},
+		{"unterminated quote", `regex("^pg-)`, "", false, "unterminated quote"},
+		{"bad pattern", `regex("[")`, "", false, "invalid pattern"},
+		{"empty enum", "enum()", "", false, "needs at least one value"},
+		{"length without bounds", "length()", "", false, "needs a min or max length"},
+		{"quantity bounds", "memory(min=256Mi,max=64Gi)", "memory", false, ""},
+		{"invalid quantity bound", "memory(min=lots)", "", false, `argument "min": must be a valid memory quantity`},
+		{"quantity min above max", "cpu(min=2,max=500m)", "", false, "min 2 is greater than max 500m"},
+		{"storage bounds", "storage(min=1Gi,max=10Ti)", "storage", false, ""},
+		{"cron dialect", "cron-schedule(seconds)", "cron-schedule", false, ""},
+		{"named cron dialect", "cron-schedule(dialect=cnpg)", "cron-schedule", false, ""},
+		{"unknown cron dialect", "cron-schedule(quartz)", "", false, `unknown cron dialect "quartz"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewValidator(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewValidator(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewValidator(%q) error: %v", tt.spec, err)
+			}
+			if v.Name != tt.wantName || v.Optional != tt.wantOptional {
+				t.Errorf("NewValidator(%q) = %s optional=%v, want %s optional=%v", tt.spec, v.Name, v.Optional, tt.wantName, tt.wantOptional)
+			}
+		})
+	}
+}
+
+func TestValidatorParse(t *testing.T) {
+	tests := []struct {
+		spec     string
+		value    string
+		wantRule string // empty when the value is valid
+	}{
+		{"integer(min=1,max=9)", "5", ""},
+		{"integer(min=1,max=9)", "0", "min=1"},
+		{"integer(min=1,max=9)", "10", "max=9"},
+		{"integer(min=1,max=9)", "x", "integer"},
+		{"integer(min=-5)", "-3", ""},
+		{"integer(max=9)", "-3", "integer"},
+		{"integer", "42", ""},
+		{`regex("^pg-")`, "pg-main", ""},
+		{`regex("^pg-")`, "main", "regex"},
+		{"enum(a,b,c)", "b", ""},
+		{"enum(a,b,c)", "d", "enum"},
+		{"length(3,20)", "abc", ""},
+		{"length(3,20)", "ab", "min=3"},
+		{"length(3,20)", strings.Repeat("a", 21), "max=20"},
+		{"length(max=3)", "äöü", ""},
+		{"string(optional)", "", ""},
+		{"memory(min=256Mi,max=64Gi)", "1Gi", ""},
+		{"memory(min=256Mi,max=64Gi)", "128Mi", "min=256Mi"},
+		{"memory(min=256Mi,max=64Gi)", "65Gi", "max=64Gi"},
+		{"memory(min=256Mi)", "100m", "memory"},
+		{"cpu(max=4)", "3500m", ""},
+		{"cpu(max=4)", "4.5", "max=4"},
+		{"storage(min=1Gi)", "500Mi", "min=1Gi"},
+		{"cron-schedule(seconds)", "0 0 0 * * *", ""},
+		{"cron-schedule(seconds)", "0 0 * * *", "cron-schedule"},
+		{"label-value(optional)", "", ""},
+		{"string", "", "string"},
+		{"dns-name(optional)", "", ""},
+		{"dns-name(optional)", "Main", "dns-name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" "+tt.value, func(t *testing.T) {
+			v, err := NewValidator(tt.spec)
+			if err != nil {
+				t.Fatalf("NewValidator(%q) error: %v", tt.spec, err)
+			}
+			obj, err := v.Parse(tt.value)
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Fatalf("Parse(%q) error: %v", tt.value, err)
+				}
+				if tt.value != "" && obj.String() != tt.value {
+					t.Errorf("Parse(%q).String() = %q", tt.value, obj.String())
+				}
+				return
+			}
+			var ruleErr *RuleError
+			if !errors.As(err, &ruleErr) {
+				t.Fatalf("Parse(%q) error = %v, want a rule error", tt.value, err)
+			}
+			if ruleErr.Rule != tt.wantRule {
+				t.Errorf("Parse(%q) failed rule %q, want %q", tt.value, ruleErr.Rule, tt.wantRule)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantRule+": ") {
+				t.Errorf("Parse(%q) error %q does not name rule %q", tt.value, err, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestRegisterValidationType(t *testing.T) {
+	RegisterValidationType("even", func(args ValidationArgs) ([]ValidationRule, error) {
+		if err := args.Expect(); err != nil {
+			return nil, err
+		}
+		return []ValidationRule{{Name: "even", Check: func(s string) (ValueObject, error) {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("must be a valid integer")
+			}
+			if n%2 != 0 {
+				return nil, fmt.Errorf("must be even")
+			}
+			return Integer{value: s}, nil
+		}}}, nil
+	})
+	t.Cleanup(func() { delete(validationTypes, "even") })
+
+	if _, err := ParseValue("even", "4"); err != nil {
+		t.Errorf("ParseValue(even, 4) error: %v", err)
+	}
+	if _, err := ParseValue("even", "3"); err == nil || err.Error() != "even: must be even" {
+		t.Errorf("ParseValue(even, 3) error = %v, want %q", err, "even: must be even")
+	}
+	if _, err := ParseValue("even(optional)", ""); err != nil {
+		t.Errorf("ParseValue(even(optional), \"\") error: %v", err)
+	}
+}
+
+func TestValidationTypeName(t *testing.T) {
+	for spec, want := range map[string]string{
+		"integer":              "integer",
+		"integer(min=1,max=9)": "integer",
+		" port ":               "port",
+	} {
+		if got := ValidationTypeName(spec); got != want {
+			t.Errorf("ValidationTypeName(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
@@ -2,9 +2,16 @@ package domain
 
 import (
 	"fmt"
+	"math"
+	"net/mail"
+	"net/netip"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 // ValueObject represents a validated domain value.
@@ -12,31 +19,22 @@ type ValueObject interface {
 	String() string
 }
 
-// ParseValue creates a validated value object from a type name and raw string.
-// Returns an error if the value is invalid for the given type.
+// ParseValue creates a validated value object from a validation type, such as
+// "dns-name" or "integer(min=1,max=9)", and a raw string.
+// Returns an error naming the failed rule if the value is invalid for the type.
 func ParseValue(typeName string, value string) (ValueObject, error) {
-	switch typeName {
-	case "dns-name":
-		return NewDNSName(value)
-	case "integer":
-		return NewInteger(value)
-	case "string":
-		return NewNonEmptyString(value)
-	case "port":
-		return NewPort(value)
-	case "memory":
-		return NewMemory(value)
-	case "cpu":
-		return NewCPU(value)
-	case "cron-schedule":
-		return NewCronSchedule(value)
-	case "filename":
-		return NewFilename(value)
-	case "path":
-		return NewPath(value)
-	default:
-		return nil, fmt.Errorf("unknown validation type: %q", typeName)
+	v, err := NewValidator(typeName)
+	if err != nil {
+		return nil, err
 	}
+	return v.Parse(value)
+}
+
+// QuantityValue is a value object holding a Kubernetes resource quantity, whose
+// String is the canonical form the Kubernetes API stores.
+type QuantityValue interface {
+	ValueObject
+	Quantity() resource.Quantity
 }
 
 // DNSName is a valid RFC 1123 DNS label.
@@ -109,63 +107,87 @@ func NewPort(s string) (Port, error) {
 
 func (p Port) String() string { return p.value }
 
-// Memory is a valid Kubernetes memory resource quantity.
-type Memory struct{ value string }
-
-var memoryRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti|Pi|Ei|k|M|G|T|P|E)?$`)
+// Memory is a positive Kubernetes memory quantity, in canonical form.
+type Memory struct{ quantity resource.Quantity }
 
 func NewMemory(s string) (Memory, error) {
-	if s == "" {
-		return Memory{}, fmt.Errorf("memory value cannot be empty")
-	}
-	if !memoryRegexp.MatchString(s) {
-		return Memory{}, fmt.Errorf("must be a valid memory quantity (e.g., 256Mi, 1Gi)")
+	q, err := parseByteQuantity(s, "memory", "256Mi, 1Gi")
+	if err != nil {
+		return Memory{}, err
 	}
-	return Memory{value: s}, nil
+	return Memory{quantity: q}, nil
 }
 
-func (m Memory) String() string { return m.value }
+func (m Memory) String() string { return m.quantity.String() }
 
-// CPU is a valid Kubernetes CPU resource quantity.
-type CPU struct{ value string }
+// Quantity returns the memory as a resource quantity.
+func (m Memory) Quantity() resource.Quantity { return m.quantity }
 
-var cpuRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?m?$`)
+// CPU is a positive Kubernetes CPU quantity, in canonical form.
+type CPU struct{ quantity resource.Quantity }
 
 func NewCPU(s string) (CPU, error) {
+	q, err := parseQuantity(s, "cpu", "100m, 0.5, 2")
+	if err != nil {
+		return CPU{}, err
+	}
+	return CPU{quantity: q}, nil
+}
+
+func (c CPU) String() string { return c.quantity.String() }
+
+// Quantity returns the CPU as a resource quantity.
+func (c CPU) Quantity() resource.Quantity { return c.quantity }
+
+// parseQuantity parses a positive resource quantity, as the Kubernetes API does,
+// ignoring surrounding whitespace.
+func parseQuantity(s, kind, examples string) (resource.Quantity, error) {
+	s = strings.TrimSpace(s)
 	if s == "" {
-		return CPU{}, fmt.Errorf("cpu value cannot be empty")
+		return resource.Quantity{}, fmt.Errorf("%s value cannot be empty", kind)
+	}
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("must be a valid %s quantity (e.g., %s)", kind, examples)
 	}
-	if !cpuRegexp.MatchString(s) {
-		return CPU{}, fmt.Errorf("must be a valid cpu quantity (e.g., 100m, 0.5, 2)")
+	if q.Sign() <= 0 {
+		return resource.Quantity{}, fmt.Errorf("%s must be greater than zero", kind)
 	}
-	return CPU{value: s}, nil
+	return q, nil
 }
 
-func (c CPU) String() string { return c.value }
+// StorageSize is a positive Kubernetes storage quantity, such as the size of a
+// persistent volume, in canonical form.
+type StorageSize struct{ quantity resource.Quantity }
+
+func NewStorageSize(s string) (StorageSize, error) {
+	q, err := parseByteQuantity(s, "storage", "10Gi, 1Ti")
+	if err != nil {
+		return StorageSize{}, err
+	}
+	return StorageSize{quantity: q}, nil
+}
 
-// CronSchedule is a valid 5-field standard cron expression.
-type CronSchedule struct{ value string }
+func (z StorageSize) String() string { return z.quantity.String() }
 
-var cronFieldRegexp = regexp.MustCompile(`^(\*|[0-9]+(-[0-9]+)?)(\/[0-9]+)?(,(\*|[0-9]+(-[0-9]+)?)(\/[0-9]+)?)*$`)
+// Quantity returns the storage size as a resource quantity.
+func (z StorageSize) Quantity() resource.Quantity { return z.quantity }
 
-func NewCronSchedule(s string) (CronSchedule, error) {
-	if strings.TrimSpace(s) == "" {
-		return CronSchedule{}, fmt.Errorf("cron schedule cannot be empty")
-	}
-	fields := strings.Fields(s)
-	if len(fields) != 5 {
-		return CronSchedule{}, fmt.Errorf("cron schedule must have exactly 5 fields (minute hour day month weekday)")
+// parseByteQuantity parses a positive quantity of whole bytes.
+func parseByteQuantity(s, kind, examples string) (resource.Quantity, error) {
+	q, err := parseQuantity(s, kind, examples)
+	if err != nil {
+		return resource.Quantity{}, err
 	}
-	for _, field := range fields {
-		if !cronFieldRegexp.MatchString(field) {
-			return CronSchedule{}, fmt.Errorf("invalid cron field %q: must contain numbers, *, -, /, or ,", field)
+	if q.MilliValue()%1000 != 0 {
+		if milli, ok := strings.CutSuffix(strings.TrimSpace(s), "m"); ok {
+			return resource.Quantity{}, fmt.Errorf("%s must be a whole number of bytes: %sm is millibytes, did you mean %sMi?", kind, milli, milli)
 		}
+		return resource.Quantity{}, fmt.Errorf("%s must be a whole number of bytes", kind)
 	}
-	return CronSchedule{value: s}, nil
+	return q, nil
 }
 
-func (c CronSchedule) String() string { return c.value }
-
 // Filename is a validated output filename (no path separators or traversal).
 type Filename struct{ value string }
 
@@ -196,3 +218,265 @@ func NewPath(s string) (Path, error) {
 }
 
 func (p Path) String() string { return p.value }
+
+// DNSSubdomain is a valid RFC 1123 DNS subdomain: dot-separated DNS labels,
+// 253 characters at most, as most Kubernetes object names are.
+type DNSSubdomain struct{ value string }
+
+var dnsSubdomainRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+func NewDNSSubdomain(s string) (DNSSubdomain, error) {
+	if s == "" {
+		return DNSSubdomain{}, fmt.Errorf("dns subdomain cannot be empty")
+	}
+	if len(s) > 253 {
+		return DNSSubdomain{}, fmt.Errorf("dns subdomain must be 253 characters or fewer")
+	}
+	if !dnsSubdomainRegexp.MatchString(s) {
+		return DNSSubdomain{}, fmt.Errorf("must be a valid dns subdomain: lowercase alphanumeric, hyphens and dots, each part starting and ending with alphanumeric")
+	}
+	return DNSSubdomain{value: s}, nil
+}
+
+func (d DNSSubdomain) String() string { return d.value }
+
+// ImageReference is a container image reference: an optional registry, a
+// repository path, and an optional tag and digest, as in
+// "ghcr.io/cloudnative-pg/postgresql:16.4@sha256:...".
+type ImageReference struct{ value string }
+
+var imageReferenceRegexp = regexp.MustCompile(`^` +
+	// Registry host, with an optional port
+	`(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(?::[0-9]+)?/)?` +
+	// Repository path
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	// Tag and digest
+	`(?::[\w][\w.-]{0,127})?(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?$`)
+
+func NewImageReference(s string) (ImageReference, error) {
+	if s == "" {
+		return ImageReference{}, fmt.Errorf("image reference cannot be empty")
+	}
+	if !imageReferenceRegexp.MatchString(s) {
+		return ImageReference{}, fmt.Errorf("must be a valid image reference (e.g., postgres:16, ghcr.io/cloudnative-pg/postgresql:16.4)")
+	}
+	return ImageReference{value: s}, nil
+}
+
+func (i ImageReference) String() string { return i.value }
+
+// labelNameRegexp matches the name part of a label key, and label values.
+var labelNameRegexp = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+
+// LabelKey is a valid Kubernetes label or annotation key: a name of up to 63
+// characters, optionally prefixed by a DNS subdomain and a slash.
+type LabelKey struct{ value string }
+
+func NewLabelKey(s string) (LabelKey, error) {
+	if s == "" {
+		return LabelKey{}, fmt.Errorf("label key cannot be empty")
+	}
+	name := s
+	if prefix, rest, ok := strings.Cut(s, "/"); ok {
+		if _, err := NewDNSSubdomain(prefix); err != nil {
+			return LabelKey{}, fmt.Errorf("label key prefix %q: %w", prefix, err)
+		}
+		name = rest
+	}
+	if len(name) > 63 {
+		return LabelKey{}, fmt.Errorf("label key name must be 63 characters or fewer")
+	}
+	if !labelNameRegexp.MatchString(name) {
+		return LabelKey{}, fmt.Errorf("must be a valid label key: alphanumeric, '-', '_' and '.', must start and end with alphanumeric, optionally prefixed by a dns subdomain and '/'")
+	}
+	return LabelKey{value: s}, nil
+}
+
+func (l LabelKey) String() string { return l.value }
+
+// LabelValue is a valid, non-empty Kubernetes label value.
+type LabelValue struct{ value string }
+
+func NewLabelValue(s string) (LabelValue, error) {
+	if s == "" {
+		return LabelValue{}, fmt.Errorf("label value cannot be empty")
+	}
+	if len(s) > 63 {
+		return LabelValue{}, fmt.Errorf("label value must be 63 characters or fewer")
+	}
+	if !labelNameRegexp.MatchString(s) {
+		return LabelValue{}, fmt.Errorf("must be a valid label value: alphanumeric, '-', '_' and '.', must start and end with alphanumeric")
+	}
+	return LabelValue{value: s}, nil
+}
+
+func (l LabelValue) String() string { return l.value }
+
+// URL is an absolute URL with a scheme and host, such as an S3 endpoint.
+type URL struct {
+	value string
+	url   *url.URL
+}
+
+func NewURL(s string) (URL, error) {
+	if s == "" {
+		return URL{}, fmt.Errorf("url cannot be empty")
+	}
+	u, err := url.Parse(s)
+	if err != nil || strings.ContainsAny(s, " \t\n") || u.Scheme == "" || u.Host == "" {
+		return URL{}, fmt.Errorf("must be an absolute url with a scheme and host (e.g., https://s3.eu-west-1.amazonaws.com)")
+	}
+	return URL{value: s, url: u}, nil
+}
+
+func (u URL) String() string { return u.value }
+
+// URL returns the parsed URL.
+func (u URL) URL() *url.URL { return u.url }
+
+// Duration is a positive duration of whole units, such as "30d", "12h" or
+// "1h30m". Units are ms, s, m, h, d (24 hours) and w (7 days).
+type Duration struct {
+	value    string
+	duration time.Duration
+}
+
+var (
+	durationRegexp     = regexp.MustCompile(`^([0-9]+(ms|s|m|h|d|w))+$`)
+	durationPartRegexp = regexp.MustCompile(`([0-9]+)(ms|s|m|h|d|w)`)
+	durationUnits      = map[string]time.Duration{
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+	}
+)
+
+func NewDuration(s string) (Duration, error) {
+	if s == "" {
+		return Duration{}, fmt.Errorf("duration cannot be empty")
+	}
+	if !durationRegexp.MatchString(s) {
+		return Duration{}, fmt.Errorf("must be a valid duration of numbers and units ms, s, m, h, d or w (e.g., 30d, 1h30m)")
+	}
+	var total time.Duration
+	for _, part := range durationPartRegexp.FindAllStringSubmatch(s, -1) {
+		n, err := strconv.ParseInt(part[1], 10, 64)
+		if err != nil || n > int64(math.MaxInt64/durationUnits[part[2]]) {
+			return Duration{}, fmt.Errorf("duration is too long")
+		}
+		total += time.Duration(n) * durationUnits[part[2]]
+		if total < 0 {
+			return Duration{}, fmt.Errorf("duration is too long")
+		}
+	}
+	if total == 0 {
+		return Duration{}, fmt.Errorf("duration must be greater than zero")
+	}
+	return Duration{value: s, duration: total}, nil
+}
+
+func (d Duration) String() string { return d.value }
+
+// Duration returns the length of the duration.
+func (d Duration) Duration() time.Duration { return d.duration }
+
+// SemVer is a semantic version as defined by semver.org, such as "1.4.0" or
+// "2.0.0-rc.1+build.5", without a leading "v".
+type SemVer struct{ value string }
+
+var semVerRegexp = regexp.MustCompile(`^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)` +
+	`(?:-((?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+func NewSemVer(s string) (SemVer, error) {
+	if s == "" {
+		return SemVer{}, fmt.Errorf("version cannot be empty")
+	}
+	if !semVerRegexp.MatchString(s) {
+		if semVerRegexp.MatchString(strings.TrimPrefix(s, "v")) {
+			return SemVer{}, fmt.Errorf("must be a semantic version without a leading v: did you mean %s?", strings.TrimPrefix(s, "v"))
+		}
+		return SemVer{}, fmt.Errorf("must be a semantic version (e.g., 1.4.0, 2.0.0-rc.1)")
+	}
+	return SemVer{value: s}, nil
+}
+
+func (v SemVer) String() string { return v.value }
+
+// Boolean is "true" or "false".
+type Boolean struct{ value bool }
+
+func NewBoolean(s string) (Boolean, error) {
+	switch s {
+	case "true":
+		return Boolean{value: true}, nil
+	case "false":
+		return Boolean{value: false}, nil
+	}
+	return Boolean{}, fmt.Errorf("must be true or false")
+}
+
+func (b Boolean) String() string { return strconv.FormatBool(b.value) }
+
+// Bool returns the value as a bool.
+func (b Boolean) Bool() bool { return b.value }
+
+// IPAddress is an IPv4 or IPv6 address.
+type IPAddress struct{ addr netip.Addr }
+
+func NewIPAddress(s string) (IPAddress, error) {
+	if s == "" {
+		return IPAddress{}, fmt.Errorf("ip address cannot be empty")
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return IPAddress{}, fmt.Errorf("must be a valid IPv4 or IPv6 address (e.g., 10.0.0.1, fd00::1)")
+	}
+	return IPAddress{addr: addr}, nil
+}
+
+func (a IPAddress) String() string { return a.addr.String() }
+
+// Addr returns the address.
+func (a IPAddress) Addr() netip.Addr { return a.addr }
+
+// CIDR is an IPv4 or IPv6 network in CIDR notation, without host bits set.
+type CIDR struct{ prefix netip.Prefix }
+
+func NewCIDR(s string) (CIDR, error) {
+	if s == "" {
+		return CIDR{}, fmt.Errorf("cidr cannot be empty")
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return CIDR{}, fmt.Errorf("must be a valid CIDR (e.g., 10.0.0.0/8, fd00::/64)")
+	}
+	if masked := prefix.Masked(); masked != prefix {
+		return CIDR{}, fmt.Errorf("cidr has host bits set: did you mean %s?", masked)
+	}
+	return CIDR{prefix: prefix}, nil
+}
+
+func (c CIDR) String() string { return c.prefix.String() }
+
+// Prefix returns the network.
+func (c CIDR) Prefix() netip.Prefix { return c.prefix }
+
+// Email is a bare email address, without a display name.
+type Email struct{ value string }
+
+func NewEmail(s string) (Email, error) {
+	if s == "" {
+		return Email{}, fmt.Errorf("email cannot be empty")
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return Email{}, fmt.Errorf("must be a valid email address (e.g., dba@example.com)")
+	}
+	return Email{value: s}, nil
+}
+
+func (e Email) String() string { return e.value }
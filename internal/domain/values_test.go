@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewDNSName(t *testing.T) {
@@ -120,15 +122,24 @@ func TestNewMemory(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   string
+		want    string
 		wantErr bool
 	}{
-		{"valid Mi", "256Mi", false},
-		{"valid Gi", "4Gi", false},
-		{"valid plain", "1024", false},
-		{"valid decimal", "1.5Gi", false},
-		{"empty", "", true},
-		{"invalid suffix", "4GB", true},
-		{"text", "lots", true},
+		{"valid Mi", "256Mi", "256Mi", false},
+		{"valid Gi", "4Gi", "4Gi", false},
+		{"valid plain", "1024", "1024", false},
+		{"decimal canonicalised", "1.5Gi", "1536Mi", false},
+		{"binary canonicalised", "1024Mi", "1Gi", false},
+		{"decimal suffix", "2G", "2G", false},
+		{"exponent", "1e3", "1e3", false},
+		{"surrounding whitespace", " 2Gi ", "2Gi", false},
+		{"empty", "", "", true},
+		{"zero", "0", "", true},
+		{"negative", "-1Gi", "", true},
+		{"millibytes", "100m", "", true},
+		{"fractional bytes", "0.5", "", true},
+		{"invalid suffix", "4GB", "", true},
+		{"text", "lots", "", true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -136,8 +147,8 @@ func TestNewMemory(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewMemory(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
 			}
-			if err == nil && v.String() != tt.input {
-				t.Errorf("NewMemory(%q).String() = %q, want %q", tt.input, v.String(), tt.input)
+			if err == nil && v.String() != tt.want {
+				t.Errorf("NewMemory(%q).String() = %q, want %q", tt.input, v.String(), tt.want)
 			}
 		})
 	}
@@ -147,14 +158,19 @@ func TestNewCPU(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   string
+		want    string
 		wantErr bool
 	}{
-		{"valid millicore", "100m", false},
-		{"valid whole", "2", false},
-		{"valid decimal", "0.5", false},
-		{"empty", "", true},
-		{"text", "fast", true},
-		{"invalid suffix", "2cores", true},
+		{"valid millicore", "100m", "100m", false},
+		{"valid whole", "2", "2", false},
+		{"decimal canonicalised", "0.5", "500m", false},
+		{"millicores canonicalised", "2000m", "2", false},
+		{"exponent", "2e0", "2e0", false},
+		{"empty", "", "", true},
+		{"zero", "0", "", true},
+		{"negative", "-1", "", true},
+		{"text", "fast", "", true},
+		{"invalid suffix", "2cores", "", true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -162,41 +178,8 @@ func TestNewCPU(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewCPU(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
 			}
-			if err == nil && v.String() != tt.input {
-				t.Errorf("NewCPU(%q).String() = %q, want %q", tt.input, v.String(), tt.input)
-			}
-		})
-	}
-}
-
-func TestNewCronSchedule(t *testing.T) {
-	tests := []struct {
-		name    string
-		input   string
-		wantErr bool
-	}{
-		{"every minute", "* * * * *", false},
-		{"midnight daily", "0 0 * * *", false},
-		{"weekdays at 5am", "0 5 * * 1-5", false},
-		{"every 5 minutes", "*/5 * * * *", false},
-		{"complex", "0,30 9-17 * * 1-5", false},
-		{"specific day and time", "15 14 1 * *", false},
-		{"empty", "", true},
-		{"whitespace only", "   ", true},
-		{"too few fields", "* * *", true},
-		{"too many fields", "* * * * * *", true},
-		{"invalid characters", "0 0 * * abc", true},
-		{"not a cron", "not a cron", true},
-		{"single field", "0", true},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			v, err := NewCronSchedule(tt.input)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("NewCronSchedule(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
-			}
-			if err == nil && v.String() != tt.input {
-				t.Errorf("NewCronSchedule(%q).String() = %q, want %q", tt.input, v.String(), tt.input)
+			if err == nil && v.String() != tt.want {
+				t.Errorf("NewCPU(%q).String() = %q, want %q", tt.input, v.String(), tt.want)
 			}
 		})
 	}
@@ -257,6 +240,351 @@ func TestNewPath(t *testing.T) {
 	}
 }
 
+func TestNewStorageSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"valid Gi", "10Gi", "10Gi", false},
+		{"valid Ti", "1Ti", "1Ti", false},
+		{"binary canonicalised", "1024Gi", "1Ti", false},
+		{"decimal suffix", "500G", "500G", false},
+		{"empty", "", "", true},
+		{"zero", "0", "", true},
+		{"negative", "-10Gi", "", true},
+		{"millibytes", "10m", "", true},
+		{"invalid suffix", "10GB", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewStorageSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewStorageSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && v.String() != tt.want {
+				t.Errorf("NewStorageSize(%q).String() = %q, want %q", tt.input, v.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDNSSubdomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"simple", "mydb", false},
+		{"dotted", "backups.example.com", false},
+		{"hyphens", "pg-1.eu-west", false},
+		{"max length", strings.Repeat("a.", 126) + "a", false},
+		{"empty", "", true},
+		{"too long", strings.Repeat("a.", 126) + "ab", true},
+		{"uppercase", "MyDB.example", true},
+		{"leading dot", ".example", true},
+		{"trailing dot", "example.", true},
+		{"empty label", "a..b", true},
+		{"label ends with hyphen", "a-.b", true},
+		{"underscore", "my_db", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDNSSubdomain(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewDNSSubdomain(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewImageReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"name only", "postgres", false},
+		{"with tag", "postgres:16", false},
+		{"with path", "cloudnative-pg/postgresql:16.4", false},
+		{"with registry", "ghcr.io/cloudnative-pg/postgresql:16.4-27", false},
+		{"with registry port", "localhost:5000/postgres:16", false},
+		{"with digest", "postgres@sha256:" + strings.Repeat("a1", 32), false},
+		{"with tag and digest", "ghcr.io/cloudnative-pg/postgresql:16.4@sha256:" + strings.Repeat("b2", 32), false},
+		{"empty", "", true},
+		{"uppercase repository", "Postgres:16", true},
+		{"empty tag", "postgres:", true},
+		{"tag with leading hyphen", "postgres:-16", true},
+		{"short digest", "postgres@sha256:abc", true},
+		{"whitespace", "postgres 16", true},
+		{"scheme", "https://ghcr.io/postgres", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewImageReference(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewImageReference(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewLabelKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"name only", "tier", false},
+		{"with dots and underscores", "cost_center.team", false},
+		{"prefixed", "app.kubernetes.io/name", false},
+		{"mixed case", "Tier", false},
+		{"empty", "", true},
+		{"empty name", "example.com/", true},
+		{"empty prefix", "/tier", true},
+		{"invalid prefix", "Example.com/tier", true},
+		{"two slashes", "a/b/c", true},
+		{"name too long", strings.Repeat("a", 64), true},
+		{"leading hyphen", "-tier", true},
+		{"space", "my tier", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLabelKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewLabelKey(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewLabelValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"simple", "production", false},
+		{"with punctuation", "v1.2_rc-3", false},
+		{"max length", strings.Repeat("a", 63), false},
+		{"empty", "", true},
+		{"too long", strings.Repeat("a", 64), true},
+		{"slash", "a/b", true},
+		{"trailing dot", "v1.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLabelValue(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewLabelValue(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"https", "https://s3.eu-west-1.amazonaws.com", false},
+		{"with port and path", "http://minio.storage:9000/backups", false},
+		{"s3 scheme", "s3://backups/pg", false},
+		{"empty", "", true},
+		{"no scheme", "s3.amazonaws.com", true},
+		{"no host", "file:///tmp/backups", true},
+		{"whitespace", "https://example.com/a b", true},
+		{"invalid", "http://[::1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewURL(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewURL(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && v.String() != tt.input {
+				t.Errorf("NewURL(%q).String() = %q, want %q", tt.input, v.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestNewDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"days", "30d", 30 * 24 * time.Hour, false},
+		{"weeks", "2w", 14 * 24 * time.Hour, false},
+		{"hours and minutes", "1h30m", 90 * time.Minute, false},
+		{"seconds", "45s", 45 * time.Second, false},
+		{"milliseconds", "250ms", 250 * time.Millisecond, false},
+		{"empty", "", 0, true},
+		{"zero", "0d", 0, true},
+		{"no unit", "30", 0, true},
+		{"unknown unit", "1y", 0, true},
+		{"fraction", "1.5h", 0, true},
+		{"negative", "-1h", 0, true},
+		{"space", "1h 30m", 0, true},
+		{"too long", "9999999999999w", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && v.Duration() != tt.want {
+				t.Errorf("NewDuration(%q).Duration() = %v, want %v", tt.input, v.Duration(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSemVer(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"release", "1.4.0", false},
+		{"zero", "0.0.0", false},
+		{"prerelease", "2.0.0-rc.1", false},
+		{"build metadata", "1.0.0+build.5", false},
+		{"prerelease and build", "1.0.0-alpha.beta+exp.sha.5114f85", false},
+		{"empty", "", true},
+		{"leading v", "v1.4.0", true},
+		{"two parts", "1.4", true},
+		{"leading zero", "01.4.0", true},
+		{"leading zero in prerelease", "1.0.0-01", true},
+		{"empty prerelease", "1.0.0-", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSemVer(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSemVer(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewBoolean(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{"true", "true", true, false},
+		{"false", "false", false, false},
+		{"empty", "", false, true},
+		{"capitalised", "True", false, true},
+		{"yes", "yes", false, true},
+		{"number", "1", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewBoolean(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewBoolean(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && v.Bool() != tt.want {
+				t.Errorf("NewBoolean(%q).Bool() = %v, want %v", tt.input, v.Bool(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIPAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"ipv4", "10.0.0.1", "10.0.0.1", false},
+		{"ipv6", "fd00::1", "fd00::1", false},
+		{"ipv6 canonicalised", "FD00:0:0::1", "fd00::1", false},
+		{"empty", "", "", true},
+		{"out of range", "10.0.0.256", "", true},
+		{"leading zero", "10.0.0.01", "", true},
+		{"zone", "fe80::1%eth0", "", true},
+		{"cidr", "10.0.0.0/8", "", true},
+		{"hostname", "db.example.com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewIPAddress(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewIPAddress(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && v.String() != tt.want {
+				t.Errorf("NewIPAddress(%q).String() = %q, want %q", tt.input, v.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCIDR(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"ipv4", "10.0.0.0/8", ""},
+		{"single host", "192.168.1.10/32", ""},
+		{"ipv6", "fd00::/64", ""},
+		{"empty", "", "cannot be empty"},
+		{"no prefix length", "10.0.0.0", "must be a valid CIDR"},
+		{"prefix too long", "10.0.0.0/33", "must be a valid CIDR"},
+		{"host bits set", "10.0.0.1/8", "did you mean 10.0.0.0/8?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCIDR(tt.input)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("NewCIDR(%q) returned error: %v", tt.input, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewCIDR(%q) error = %v, want it to contain %q", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"simple", "dba@example.com", false},
+		{"plus tag", "dba+alerts@example.com", false},
+		{"empty", "", true},
+		{"no at", "dba.example.com", true},
+		{"no domain", "dba@", true},
+		{"display name", "DBA <dba@example.com>", true},
+		{"angle brackets", "<dba@example.com>", true},
+		{"two addresses", "a@example.com, b@example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEmail(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewEmail(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestParseValue(t *testing.T) {
 	knownTypes := []struct {
 		typeName   string
@@ -271,6 +599,18 @@ func TestParseValue(t *testing.T) {
 		{"cron-schedule", "0 0 * * *"},
 		{"filename", "manifest.yaml"},
 		{"path", "templates"},
+		{"dns-subdomain", "backups.example.com"},
+		{"storage", "10Gi"},
+		{"image", "ghcr.io/cloudnative-pg/postgresql:16.4"},
+		{"label-key", "app.kubernetes.io/name"},
+		{"label-value", "production"},
+		{"url", "https://s3.eu-west-1.amazonaws.com"},
+		{"duration", "30d"},
+		{"semver", "1.4.0"},
+		{"boolean", "true"},
+		{"ip", "10.0.0.1"},
+		{"cidr", "10.0.0.0/8"},
+		{"email", "dba@example.com"},
 	}
 	for _, tt := range knownTypes {
 		t.Run(tt.typeName, func(t *testing.T) {
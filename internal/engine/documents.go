@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"inscribe/internal/domain"
+)
+
+// outputFileMarker prefixes the comment line outputFile renders. It is a YAML
+// comment so the rendered output stays valid until SplitDocuments removes it.
+const outputFileMarker = "# inscribe-output-file:"
+
+// documentSeparator matches a line starting a new YAML document, which may
+// carry a comment or content after the marker.
+var documentSeparator = regexp.MustCompile(`^---(\s|#|$)`)
+
+// outputFileLine renders the marker declaring the target file of the current document.
+func outputFileLine(filename string) string {
+	return outputFileMarker + " " + filename
+}
+
+// SplitDocuments splits the rendered output of a bundle on "---" separator lines
+// into its YAML documents, dropping empty ones. Content after the separator on its
+// line, other than a comment, starts the next document. A document's target file is taken from its
+// outputFile marker, which is removed from the content.
+func SplitDocuments(rendered string) []domain.Document {
+	var docs []domain.Document
+	current := domain.Document{}
+	var lines []string
+	flush := func() {
+		content := strings.Join(lines, "\n")
+		if strings.TrimSpace(content) != "" {
+			current.Content = strings.TrimRight(content, "\n") + "\n"
+			docs = append(docs, current)
+		}
+		current, lines = domain.Document{}, nil
+	}
+
+	for _, line := range strings.Split(rendered, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case documentSeparator.MatchString(line):
+			flush()
+			if rest := strings.TrimSpace(line[len("---"):]); rest != "" && !strings.HasPrefix(rest, "#") {
+				lines = append(lines, rest)
+			}
+		case strings.HasPrefix(trimmed, outputFileMarker):
+			current.Filename = strings.TrimSpace(strings.TrimPrefix(trimmed, outputFileMarker))
+		default:
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return docs
+}
+
+// JoinDocuments joins documents into a single multi-document YAML stream.
+func JoinDocuments(docs []domain.Document) string {
+	contents := make([]string, len(docs))
+	for i, doc := range docs {
+		contents[i] = doc.Content
+	}
+	return strings.Join(contents, "---\n")
+}
+
+// DocumentFilenames returns the file each document of a bundle is written to:
+// its outputFile name, or "<template>-<n>.yaml" if it declares none.
+// Two documents may not target the same file.
+func DocumentFilenames(templateName string, docs []domain.Document) ([]string, error) {
+	names := make([]string, len(docs))
+	seen := make(map[string]bool)
+	for i, doc := range docs {
+		name := doc.Filename
+		if name == "" {
+			name = fmt.Sprintf("%s-%d.yaml", templateName, i+1)
+		}
+		if _, err := domain.NewFilename(name); err != nil {
+			return nil, fmt.Errorf("document %d: invalid output file %q: %w", i+1, name, err)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("document %d: output file %q is used by another document", i+1, name)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names, nil
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"inscribe/internal/domain"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	rendered := `---
+# inscribe-output-file: db.yaml
+kind: Cluster
+spec:
+  script: |
+    a
+    ---
+--- # backups
+kind: Backup
+----
+---
+--- {kind: Pooler}
+`
+	docs := SplitDocuments(rendered)
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 documents, got %d: %+v", len(docs), docs)
+	}
+	if docs[0].Filename != "db.yaml" {
+		t.Errorf("docs[0].Filename = %q, want db.yaml", docs[0].Filename)
+	}
+	if want := "kind: Cluster\nspec:\n  script: |\n    a\n    ---\n"; docs[0].Content != want {
+		t.Errorf("docs[0].Content = %q, want %q", docs[0].Content, want)
+	}
+	if docs[1].Filename != "" || docs[1].Content != "kind: Backup\n----\n" {
+		t.Errorf("docs[1] = %+v", docs[1])
+	}
+	if docs[2].Content != "{kind: Pooler}\n" {
+		t.Errorf("docs[2] = %+v", docs[2])
+	}
+
+	if got, want := JoinDocuments(docs), "kind: Cluster\nspec:\n  script: |\n    a\n    ---\n---\nkind: Backup\n----\n---\n{kind: Pooler}\n"; got != want {
+		t.Errorf("JoinDocuments() = %q, want %q", got, want)
+	}
+}
+
+func TestDocumentFilenames(t *testing.T) {
+	tests := []struct {
+		name    string
+		docs    []domain.Document
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "declared and fallback names",
+			docs: []domain.Document{{Filename: "db.yaml"}, {}},
+			want: []string{"db.yaml", "bundle-2.yaml"},
+		},
+		{
+			name:    "duplicate names",
+			docs:    []domain.Document{{Filename: "db.yaml"}, {Filename: "db.yaml"}},
+			wantErr: true,
+		},
+		{
+			name:    "path separator",
+			docs:    []domain.Document{{Filename: "../db.yaml"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DocumentFilenames("bundle", tt.docs)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DocumentFilenames() error: %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("DocumentFilenames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParserRenderBundle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "bundle.yaml"),
+		`{{/* inscribe: type="template" name="db-bundle" command="bundle db" description="DB bundle" bundle="true" */}}
+{{ outputFile (printf "%s-cluster.yaml" (input "name" "dns-name")) }}
+kind: Cluster
+name: {{ input "name" "dns-name" }}
+---
+{{ outputFile (printf "%s-backup.yaml" .name) }}
+kind: ScheduledBackup
+cluster: {{ .name }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	meta, err := reg.GetTemplate("db-bundle")
+	if err != nil {
+		t.Fatalf("GetTemplate() error: %v", err)
+	}
+	if !meta.Bundle {
+		t.Error("expected bundle=\"true\" header to mark the template as a bundle")
+	}
+
+	parser := NewParser(reg)
+	fields, err := parser.ExtractFields("db-bundle")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if len(fields) != 2 || fields[0].Name != "name" {
+		t.Fatalf("unexpected fields: %+v", fields)
+	}
+
+	rendered, err := parser.Render("db-bundle", map[string]string{"name": "orders"})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	docs := SplitDocuments(rendered)
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+	if docs[0].Filename != "orders-cluster.yaml" || docs[1].Filename != "orders-backup.yaml" {
+		t.Errorf("filenames = %q, %q", docs[0].Filename, docs[1].Filename)
+	}
+	if docs[1].Content != "kind: ScheduledBackup\ncluster: orders\n" {
+		t.Errorf("docs[1].Content = %q", docs[1].Content)
+	}
+}
@@ -0,0 +1,621 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"text/template"
+	"text/template/parse"
+
+	"inscribe/internal/domain"
+)
+
+// treeWalker visits a parsed template in document order and records every field
+// function call, including calls in branches that a single execution would skip.
+// Fields inside if/with/range blocks carry the block's condition so that callers
+// can decide later whether to ask for them.
+//
+// Sub-templates are templates too: after a templateGroup call the walker descends into
+// every sub-template of the group, conditioning their fields on that sub-template
+// being the selected one. Included partials are walked in place, so their fields
+// share the condition of the include.
+//
+// Conditions are recorded as template source evaluated against the answers, so
+// variables in them are replaced by the pipelines declaring them. A condition that
+// cannot stand on its own, using a range variable or the dot of a range or with
+// block, is not recorded and the fields it guards are asked unconditionally.
+type treeWalker struct {
+	tmpl      *template.Template
+	tree      *parse.Tree
+	extractor *fieldExtractor
+	funcs     template.FuncMap
+	registry  domain.TemplateRegistry
+	visiting  map[string]bool
+	vars      map[string]*parse.PipeNode // variable → declaring pipeline; nil if unknown
+	dotMoved  bool                       // inside a range or with block, where . is not the answers
+}
+
+func newTreeWalker(tmpl *template.Template, extractor *fieldExtractor, registry domain.TemplateRegistry) *treeWalker {
+	return &treeWalker{
+		tmpl:      tmpl,
+		tree:      tmpl.Tree,
+		extractor: extractor,
+		funcs:     extractor.funcMap(),
+		registry:  registry,
+		visiting:  map[string]bool{tmpl.Name(): true},
+		vars:      make(map[string]*parse.PipeNode),
+	}
+}
+
+// extract walks the root of the walker's template.
+func (w *treeWalker) extract() error {
+	if w.tree == nil {
+		return nil
+	}
+	return w.walk(w.tree.Root)
+}
+
+func (w *treeWalker) walk(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := w.walk(child); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		w.declare(n.Pipe, false)
+		return w.walkPipe(n.Pipe)
+	case *parse.IfNode:
+		return w.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return w.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return w.walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		if err := w.walkPipe(n.Pipe); err != nil {
+			return err
+		}
+		return w.walkTemplate(n.Name)
+	}
+	return nil
+}
+
+// walkBranch collects fields from the branch's pipeline, then from both of its
+// lists under the pipeline's condition and its negation respectively. The first
+// list of a range or with block runs with the pipeline's value as dot.
+func (w *treeWalker) walkBranch(b *parse.BranchNode) error {
+	if err := w.walkPipe(b.Pipe); err != nil {
+		return err
+	}
+
+	outerCondition, outerDeps := w.extractor.condition, w.extractor.dependsOn
+	outerVars, outerDot := w.vars, w.dotMoved
+	w.vars = maps.Clone(outerVars)
+	defer func() {
+		w.extractor.condition, w.extractor.dependsOn = outerCondition, outerDeps
+		// A variable assigned within the branch may or may not have changed after it.
+		for name, pipe := range outerVars {
+			if w.vars[name] != pipe {
+				outerVars[name] = nil
+			}
+		}
+		w.vars, w.dotMoved = outerVars, outerDot
+	}()
+
+	cond, ok := w.condition(b.Pipe)
+	if ok {
+		w.extractor.dependsOn = appendUnique(outerDeps, referencedFields(cond)...)
+	} else {
+		w.extractor.dependsOn = appendUnique(outerDeps, referencedFields(b.Pipe)...)
+	}
+	// Range variables take each element in turn, so they are never known.
+	w.declare(b.Pipe, b.Type() == parse.NodeRange)
+
+	w.extractor.condition = outerCondition
+	if ok {
+		w.extractor.condition = joinConditions(outerCondition, cond.String())
+	}
+	w.dotMoved = outerDot || b.Type() != parse.NodeIf
+	if err := w.walk(b.List); err != nil {
+		return err
+	}
+	w.dotMoved = outerDot
+	if b.ElseList != nil {
+		if ok {
+			w.extractor.condition = joinConditions(outerCondition, "not ("+cond.String()+")")
+		}
+		if err := w.walk(b.ElseList); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declare records the variables pipe declares or assigns, as the pipeline they
+// hold, or as unknown if unknown is set or the pipeline cannot stand on its own.
+func (w *treeWalker) declare(pipe *parse.PipeNode, unknown bool) {
+	if len(pipe.Decl) == 0 {
+		return
+	}
+	value, ok := w.condition(pipe)
+	for _, v := range pipe.Decl {
+		if unknown || !ok || len(pipe.Decl) > 1 {
+			w.vars[v.Ident[0]] = nil
+			continue
+		}
+		w.vars[v.Ident[0]] = value
+	}
+}
+
+// condition returns a copy of pipe that can be evaluated on its own against the
+// answers: without declarations, and with variables replaced by their pipelines.
+// It reports false if pipe uses an unknown variable or a moved dot.
+func (w *treeWalker) condition(pipe *parse.PipeNode) (*parse.PipeNode, bool) {
+	p := pipe.CopyPipe()
+	p.Decl = nil
+	p.IsAssign = false
+	return p, w.substitute(p)
+}
+
+// substitute replaces the variables in pipe by their pipelines, in place.
+func (w *treeWalker) substitute(pipe *parse.PipeNode) bool {
+	for _, cmd := range pipe.Cmds {
+		for i, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.VariableNode:
+				if a.Ident[0] == "$" {
+					continue
+				}
+				value := w.vars[a.Ident[0]]
+				if value == nil {
+					return false
+				}
+				cmd.Args[i] = value.CopyPipe()
+				if len(a.Ident) > 1 {
+					cmd.Args[i] = &parse.ChainNode{NodeType: parse.NodeChain, Pos: a.Pos, Node: cmd.Args[i], Field: a.Ident[1:]}
+				}
+			case *parse.DotNode, *parse.FieldNode:
+				if w.dotMoved {
+					return false
+				}
+			case *parse.ChainNode:
+				if nested, ok := a.Node.(*parse.PipeNode); ok && !w.substitute(nested) {
+					return false
+				}
+			case *parse.PipeNode:
+				if !w.substitute(a) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// walkTemplate follows a {{ template "name" }} invocation into the named template.
+func (w *treeWalker) walkTemplate(name string) error {
+	if w.visiting[name] {
+		return nil
+	}
+	t := w.tmpl.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return fmt.Errorf("template %q not defined", name)
+	}
+	w.visiting[name] = true
+	outerTree := w.tree
+	w.tree = t.Tree
+	defer func() {
+		w.tree = outerTree
+		delete(w.visiting, name)
+	}()
+	return w.walk(t.Tree.Root)
+}
+
+// walkSubTemplates collects the fields of every sub-template in group.
+// A missing group is not an error here; it is reported when the group is resolved.
+func (w *treeWalker) walkSubTemplates(group string) error {
+	if w.registry == nil {
+		return nil
+	}
+	subs, err := w.registry.GetSubTemplates(group)
+	if err != nil {
+		return nil
+	}
+
+	outerCondition, outerDeps := w.extractor.condition, w.extractor.dependsOn
+	defer func() {
+		w.extractor.condition, w.extractor.dependsOn = outerCondition, outerDeps
+	}()
+
+	for _, sub := range subs {
+		key := "sub-template:" + sub.FilePath
+		if w.visiting[key] {
+			continue
+		}
+		t, err := template.New(sub.FilePath).Funcs(w.funcs).Parse(sub.Content)
+		if err != nil {
+			return fmt.Errorf("parsing sub-template %q: %w", sub.FilePath, err)
+		}
+
+		w.extractor.condition = joinConditions(outerCondition, fmt.Sprintf("subTemplateSelected %q %q", group, sub.Description))
+		w.extractor.dependsOn = appendUnique(outerDeps, group)
+
+		if err := w.walkChild(key, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkInclude collects the fields of a partial included with {{ include "name" . }}.
+func (w *treeWalker) walkInclude(cmd *parse.CommandNode) error {
+	if len(cmd.Args) < 2 {
+		return fmt.Errorf("include requires a partial name")
+	}
+	name, ok := cmd.Args[1].(*parse.StringNode)
+	if !ok {
+		return fmt.Errorf("partial name must be a string literal, got %s", cmd.Args[1])
+	}
+	if w.registry == nil {
+		return nil
+	}
+	key := "partial:" + name.Text
+	if w.visiting[key] {
+		return fmt.Errorf("partial %q includes itself", name.Text)
+	}
+	partial, err := w.registry.GetPartial(name.Text)
+	if err != nil {
+		return err
+	}
+	t, err := template.New(partial.FilePath).Funcs(w.funcs).Parse(partial.Content)
+	if err != nil {
+		return fmt.Errorf("parsing partial %q: %w", name.Text, err)
+	}
+	return w.walkChild(key, t)
+}
+
+// walkChild walks a separately parsed template (a sub-template or partial) with the
+// current condition, marking it as being visited under key.
+func (w *treeWalker) walkChild(key string, t *template.Template) error {
+	if err := foldConstants(t, w.funcs); err != nil {
+		return err
+	}
+	w.visiting[key] = true
+	defer delete(w.visiting, key)
+	child := &treeWalker{
+		tmpl:      t,
+		tree:      t.Tree,
+		extractor: w.extractor,
+		funcs:     w.funcs,
+		registry:  w.registry,
+		visiting:  w.visiting,
+		vars:      make(map[string]*parse.PipeNode),
+		dotMoved:  w.dotMoved,
+	}
+	return child.extract()
+}
+
+func (w *treeWalker) walkPipe(pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		if err := w.walkCommand(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkCommand records a field function call and descends into parenthesized arguments.
+func (w *treeWalker) walkCommand(cmd *parse.CommandNode) error {
+	if isDerive(cmd) {
+		return w.walkDerive(cmd)
+	}
+	if isInclude(cmd) {
+		if err := w.walkInclude(cmd); err != nil {
+			return fmt.Errorf("%s: %w", w.location(cmd), err)
+		}
+	}
+	if name, ok := fieldFuncName(cmd); ok {
+		if err := w.call(name, cmd); err != nil {
+			return fmt.Errorf("%s: %w", w.location(cmd), err)
+		}
+		if name == "templateGroup" {
+			group := cmd.Args[1].(*parse.StringNode).Text
+			if err := w.walkSubTemplates(group); err != nil {
+				return fmt.Errorf("%s: %w", w.location(cmd), err)
+			}
+		}
+	}
+	for _, arg := range cmd.Args {
+		if pipe, ok := arg.(*parse.PipeNode); ok {
+			if err := w.walkPipe(pipe); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkDerive records a derived field after collecting the fields its expression uses.
+// The expression is kept as template source so it can be evaluated once values are known.
+func (w *treeWalker) walkDerive(cmd *parse.CommandNode) error {
+	if len(cmd.Args) < 3 {
+		return fmt.Errorf("%s: derive requires a name and a value", w.location(cmd))
+	}
+	name, ok := cmd.Args[1].(*parse.StringNode)
+	if !ok {
+		return fmt.Errorf("%s: derived field name must be a string literal, got %s", w.location(cmd), cmd.Args[1])
+	}
+	if pipe, ok := cmd.Args[2].(*parse.PipeNode); ok {
+		if err := w.walkPipe(pipe); err != nil {
+			return err
+		}
+	}
+
+	var opts []string
+	for _, arg := range cmd.Args[3:] {
+		s, ok := arg.(*parse.StringNode)
+		if !ok {
+			return fmt.Errorf("%s: derive options must be string literals, got %s", w.location(cmd), arg)
+		}
+		opts = append(opts, s.Text)
+	}
+
+	_, err := w.extractor.collect(domain.FieldDefinition{
+		Name:       name.Text,
+		Type:       domain.FieldDerived,
+		Expression: cmd.Args[2].String(),
+	}, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", w.location(cmd), err)
+	}
+	return nil
+}
+
+// call invokes the extractor function for a field command with its literal arguments.
+func (w *treeWalker) call(name string, cmd *parse.CommandNode) error {
+	fn := reflect.ValueOf(w.funcs[name])
+	var args []reflect.Value
+	for _, arg := range cmd.Args[1:] {
+		s, ok := arg.(*parse.StringNode)
+		if !ok {
+			return fmt.Errorf("arguments to %s must be string literals or pipelines of literals, got %s", name, arg)
+		}
+		args = append(args, reflect.ValueOf(s.Text))
+	}
+
+	fnType := fn.Type()
+	if len(args) < fnType.NumIn()-1 || (!fnType.IsVariadic() && len(args) != fnType.NumIn()) {
+		return fmt.Errorf("wrong number of arguments to %s", name)
+	}
+
+	out := fn.Call(args)
+	if err, ok := out[len(out)-1].Interface().(error); ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+// answerFuncs are the functions whose results depend on answers or the cluster.
+var answerFuncs = map[string]bool{
+	"derive":              true,
+	"include":             true,
+	"lookup":              true,
+	"fieldIs":             true,
+	"subTemplateSelected": true,
+	"outputFile":          true,
+}
+
+// foldConstants replaces the pipelines of literals passed to field functions in
+// every template associated with tmpl, such as (printf "default=%d" 3), by their
+// values, so that every pass sees string literals. Pipelines reading answers or
+// variables, or calling functions that depend on answers, the cluster or generated
+// secrets, are left as they are.
+func foldConstants(tmpl *template.Template, funcs template.FuncMap) error {
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		f := &constantFolder{tree: t.Tree, funcs: funcs}
+		if err := f.node(t.Tree.Root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type constantFolder struct {
+	tree  *parse.Tree
+	funcs template.FuncMap
+}
+
+func (f *constantFolder) node(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := f.node(child); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return f.pipe(n.Pipe)
+	case *parse.IfNode:
+		return f.branch(&n.BranchNode)
+	case *parse.RangeNode:
+		return f.branch(&n.BranchNode)
+	case *parse.WithNode:
+		return f.branch(&n.BranchNode)
+	case *parse.TemplateNode:
+		return f.pipe(n.Pipe)
+	}
+	return nil
+}
+
+func (f *constantFolder) branch(b *parse.BranchNode) error {
+	if err := f.pipe(b.Pipe); err != nil {
+		return err
+	}
+	if err := f.node(b.List); err != nil {
+		return err
+	}
+	return f.node(b.ElseList)
+}
+
+func (f *constantFolder) pipe(pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		_, isField := fieldFuncName(cmd)
+		for i, arg := range cmd.Args {
+			nested, ok := arg.(*parse.PipeNode)
+			if !ok {
+				continue
+			}
+			if !isField || i == 0 || !isConstant(nested) {
+				if err := f.pipe(nested); err != nil {
+					return err
+				}
+				continue
+			}
+			v, err := f.eval(nested)
+			if err != nil {
+				location, _ := f.tree.ErrorContext(nested)
+				return fmt.Errorf("%s: evaluating argument %s: %w", location, nested, err)
+			}
+			cmd.Args[i] = &parse.StringNode{NodeType: parse.NodeString, Pos: nested.Pos, Quoted: strconv.Quote(v), Text: v}
+		}
+	}
+	return nil
+}
+
+// eval executes a constant pipeline on its own.
+func (f *constantFolder) eval(pipe *parse.PipeNode) (string, error) {
+	t, err := template.New("argument").Funcs(f.funcs).Parse("{{ " + pipe.String() + " }}")
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// isConstant reports whether node evaluates to the same value before any answer
+// is known.
+func isConstant(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.StringNode, *parse.NumberNode, *parse.BoolNode, *parse.NilNode:
+		return true
+	case *parse.IdentifierNode:
+		_, generated := generatorFuncs(nil)[n.Ident]
+		return !fieldFuncs[n.Ident] && !answerFuncs[n.Ident] && !generated
+	case *parse.PipeNode:
+		if len(n.Decl) > 0 {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if !isConstant(arg) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (w *treeWalker) location(node parse.Node) string {
+	location, _ := w.tree.ErrorContext(node)
+	return location
+}
+
+// fieldFuncName returns the function name if cmd calls a field function.
+func fieldFuncName(cmd *parse.CommandNode) (string, bool) {
+	if len(cmd.Args) == 0 {
+		return "", false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok || !fieldFuncs[ident.Ident] {
+		return "", false
+	}
+	return ident.Ident, true
+}
+
+// isInclude reports whether cmd calls include.
+func isInclude(cmd *parse.CommandNode) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "include"
+}
+
+// isDerive reports whether cmd calls derive.
+func isDerive(cmd *parse.CommandNode) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "derive"
+}
+
+// referencedFields returns the names of fields declared anywhere within pipe.
+func referencedFields(pipe *parse.PipeNode) []string {
+	var names []string
+	for _, cmd := range pipe.Cmds {
+		if _, ok := fieldFuncName(cmd); ok && len(cmd.Args) > 1 {
+			if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+				names = appendUnique(names, s.Text)
+			}
+		}
+		for _, arg := range cmd.Args {
+			if nested, ok := arg.(*parse.PipeNode); ok {
+				names = appendUnique(names, referencedFields(nested)...)
+			}
+		}
+	}
+	return names
+}
+
+// joinConditions combines an enclosing condition with a nested one.
+func joinConditions(outer, inner string) string {
+	if outer == "" {
+		return inner
+	}
+	if inner == "" {
+		return outer
+	}
+	return fmt.Sprintf("and (%s) (%s)", outer, inner)
+}
+
+// appendUnique returns a new slice with the values of add not already in base.
+func appendUnique(base []string, add ...string) []string {
+	result := append([]string(nil), base...)
+	for _, a := range add {
+		found := false
+		for _, b := range result {
+			if a == b {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, a)
+		}
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateFiles resolves the paths given to file and fileGlob. Paths are relative to
+// the directory of the rendered template, then to those of the templates it extends,
+// and must stay inside root, the template directory, after following symlinks.
+type templateFiles struct {
+	root string
+	dirs []string
+}
+
+// environmentFuncs returns the functions reading the environment and the files next
+// to a template. Without directories to search, file and fileGlob report an error.
+func environmentFuncs(files *templateFiles) template.FuncMap {
+	return template.FuncMap{
+		"env":      env,
+		"file":     files.read,
+		"fileGlob": files.glob,
+	}
+}
+
+// env returns the value of an environment variable, or fallback if it is unset.
+// Without a fallback an unset variable is an error, so a manifest is never written
+// with a value silently missing.
+func env(name string, fallback ...string) (string, error) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	if len(fallback) > 0 {
+		return fallback[0], nil
+	}
+	return "", fmt.Errorf("environment variable %q is not set", name)
+}
+
+// read returns the content of the file at name.
+func (f *templateFiles) read(name string) (string, error) {
+	if err := f.checkPattern(name); err != nil {
+		return "", err
+	}
+	for _, dir := range f.dirs {
+		path, err := f.resolve(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("file %q: %w", name, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("file %q: %w", name, err)
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("file %q not found next to the template (looked in %s)", name, strings.Join(f.dirs, ", "))
+}
+
+// glob returns the content of the files matching pattern, by base name, so a template
+// can range over them in name order. The first directory with matches is used.
+func (f *templateFiles) glob(pattern string) (map[string]string, error) {
+	if err := f.checkPattern(pattern); err != nil {
+		return nil, err
+	}
+	for _, dir := range f.dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("fileGlob %q: %w", pattern, err)
+		}
+		files := make(map[string]string)
+		for _, match := range matches {
+			path, err := f.resolve(match)
+			if err != nil {
+				return nil, fmt.Errorf("fileGlob %q: %w", pattern, err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("fileGlob %q: %w", pattern, err)
+			}
+			if info.IsDir() {
+				continue
+			}
+			name := filepath.Base(match)
+			if _, ok := files[name]; ok {
+				return nil, fmt.Errorf("fileGlob %q: several files are named %q", pattern, name)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("fileGlob %q: %w", pattern, err)
+			}
+			files[name] = string(data)
+		}
+		if len(files) > 0 {
+			return files, nil
+		}
+	}
+	return nil, fmt.Errorf("fileGlob %q matches no files next to the template (looked in %s)", pattern, strings.Join(f.dirs, ", "))
+}
+
+// checkPattern rejects paths that cannot be resolved against the template.
+func (f *templateFiles) checkPattern(name string) error {
+	if len(f.dirs) == 0 {
+		return fmt.Errorf("%q: files can only be read while rendering a template", name)
+	}
+	if filepath.IsAbs(name) {
+		return fmt.Errorf("%q: path must be relative to the template", name)
+	}
+	return nil
+}
+
+// resolve follows symlinks in path and checks that the file it names is inside the
+// template directory. A missing file is reported as fs.ErrNotExist.
+func (f *templateFiles) resolve(path string) (string, error) {
+	if !within(f.root, path) {
+		return "", fmt.Errorf("%q is outside the template directory", path)
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(f.root)
+	if err != nil {
+		return "", err
+	}
+	if !within(root, real) {
+		return "", fmt.Errorf("%q links outside the template directory", path)
+	}
+	return real, nil
+}
+
+// within reports whether path is dir or inside it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
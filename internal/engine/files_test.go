@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("INSCRIBE_TEST_TEAM", "platform")
+	t.Setenv("INSCRIBE_TEST_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		variable string
+		fallback []string
+		want     string
+		wantErr  bool
+	}{
+		{"set", "INSCRIBE_TEST_TEAM", nil, "platform", false},
+		{"set ignores fallback", "INSCRIBE_TEST_TEAM", []string{"other"}, "platform", false},
+		{"set but empty", "INSCRIBE_TEST_EMPTY", []string{"other"}, "", false},
+		{"unset with fallback", "INSCRIBE_TEST_UNSET", []string{"cluster.local"}, "cluster.local", false},
+		{"unset", "INSCRIBE_TEST_UNSET", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := env(tt.variable, tt.fallback...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("env() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("env() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFiles(t *testing.T) {
+	root := t.TempDir()
+	child := filepath.Join(root, "clusters", "dev")
+	base := filepath.Join(root, "clusters")
+	writeFile(t, filepath.Join(base, "init.sql"), "CREATE TABLE base;")
+	writeFile(t, filepath.Join(base, "postgresql.conf"), "max_connections = 100")
+	writeFile(t, filepath.Join(child, "init.sql"), "CREATE TABLE child;")
+	writeFile(t, filepath.Join(root, "shared", "common.sql"), "CREATE EXTENSION x;")
+	writeFile(t, filepath.Join(base, "sql", "01-schema.sql"), "schema")
+	writeFile(t, filepath.Join(base, "sql", "02-data.sql"), "data")
+	writeFile(t, filepath.Join(base, "dup", "a", "x.sql"), "a")
+	writeFile(t, filepath.Join(base, "dup", "b", "x.sql"), "b")
+
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "secret.txt"), "secret")
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(base, "link.txt")); err != nil {
+		t.Fatalf("creating symlink: %v", err)
+	}
+
+	files := &templateFiles{root: root, dirs: []string{child, base}}
+
+	readTests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{"own directory first", "init.sql", "CREATE TABLE child;", ""},
+		{"base directory", "postgresql.conf", "max_connections = 100", ""},
+		{"inside template directory", "../../shared/common.sql", "CREATE EXTENSION x;", ""},
+		{"missing", "missing.sql", "", `file "missing.sql" not found next to the template`},
+		{"escaping", "../../../secret.txt", "", "outside the template directory"},
+		{"absolute", filepath.Join(outside, "secret.txt"), "", "must be relative"},
+		{"symlink escaping", "link.txt", "", "links outside the template directory"},
+	}
+	for _, tt := range readTests {
+		t.Run("file "+tt.name, func(t *testing.T) {
+			got, err := files.read(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("read(%q) error = %v, want %q", tt.path, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read(%q) error: %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("read(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	globTests := []struct {
+		name    string
+		pattern string
+		want    map[string]string
+		wantErr string
+	}{
+		{"matches", "sql/*.sql", map[string]string{"01-schema.sql": "schema", "02-data.sql": "data"}, ""},
+		{"own directory first", "*.sql", map[string]string{"init.sql": "CREATE TABLE child;"}, ""},
+		{"no matches", "sql/*.conf", nil, "matches no files"},
+		{"duplicate names", "dup/*/x.sql", nil, `several files are named "x.sql"`},
+		{"escaping", "../../../*.txt", nil, "outside the template directory"},
+		{"bad pattern", "[", nil, "syntax error"},
+	}
+	for _, tt := range globTests {
+		t.Run("fileGlob "+tt.name, func(t *testing.T) {
+			got, err := files.glob(tt.pattern)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("glob(%q) error = %v, want %q", tt.pattern, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("glob(%q) error: %v", tt.pattern, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("glob(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+			for name, content := range tt.want {
+				if got[name] != content {
+					t.Errorf("glob(%q)[%q] = %q, want %q", tt.pattern, name, got[name], content)
+				}
+			}
+		})
+	}
+
+	if _, err := (&templateFiles{}).read("init.sql"); err == nil || !strings.Contains(err.Error(), "only be read while rendering") {
+		t.Errorf("expected an error without a template, got %v", err)
+	}
+}
+
+func TestParserRenderFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("INSCRIBE_TEST_TEAM", "platform")
+
+	writeFile(t, filepath.Join(dir, "cnpg", "configmap.yaml"),
+		`{{/* inscribe: type="template" name="configmap" command="configmap cnpg" */}}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ input "name" "dns-name" }}
+  labels:
+    team: {{ env "INSCRIBE_TEST_TEAM" }}
+    domain: {{ env "INSCRIBE_TEST_UNSET" "cluster.local" }}
+data:
+  postgresql.conf: |{{ file "postgresql.conf" | nindent 4 }}
+{{- range $name, $content := fileGlob "sql/*.sql" }}
+  {{ $name }}: |{{ $content | nindent 4 }}
+{{- end }}
+`)
+	writeFile(t, filepath.Join(dir, "cnpg", "postgresql.conf"), "max_connections = 100")
+	writeFile(t, filepath.Join(dir, "cnpg", "sql", "02-data.sql"), "INSERT INTO t VALUES (1);")
+	writeFile(t, filepath.Join(dir, "cnpg", "sql", "01-schema.sql"), "CREATE TABLE t (id int);")
+	writeFile(t, filepath.Join(dir, "cnpg", "missing.yaml"),
+		`{{/* inscribe: type="template" name="missing" command="missing cnpg" */}}
+data: {{ file "init.sql" }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	got, err := parser.Render("configmap", map[string]string{"name": "init"})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: init
+  labels:
+    team: platform
+    domain: cluster.local
+data:
+  postgresql.conf: |
+    max_connections = 100
+  01-schema.sql: |
+    CREATE TABLE t (id int);
+  02-data.sql: |
+    INSERT INTO t VALUES (1);
+`
+	if got != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := parser.Render("missing", nil); err == nil || !strings.Contains(err.Error(), `file "init.sql" not found`) {
+		t.Errorf("expected missing file error, got %v", err)
+	}
+}
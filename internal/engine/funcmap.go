@@ -2,95 +2,291 @@ package engine
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"text/template"
 
 	"inscribe/internal/domain"
 )
 
-// NewExtractorFuncMap returns a FuncMap for pass 1 (field extraction).
-// Each custom function appends a FieldDefinition to the collector and returns a placeholder.
-// Template execution is single-threaded, so no synchronization is needed.
-func NewExtractorFuncMap(collector *[]domain.FieldDefinition) template.FuncMap {
-	order := 0
-	return template.FuncMap{
-		"input": func(name, validationType string) string {
-			*collector = append(*collector, domain.FieldDefinition{
+// fieldFuncs names the template functions that declare a field.
+var fieldFuncs = map[string]bool{
+	"input":         true,
+	"inputList":     true,
+	"autoList":      true,
+	"templateGroup": true,
+	"staticList":    true,
+}
+
+// fieldExtractor collects FieldDefinitions from field function calls.
+// condition and dependsOn describe the enclosing conditional blocks and are
+// set by the parse tree walker before each call. fieldMeta holds metadata
+// declared in the template header, applied before the call's own options.
+type fieldExtractor struct {
+	collector *[]domain.FieldDefinition
+	order     int
+	condition string
+	dependsOn []string
+	fieldMeta map[string]domain.FieldMeta
+}
+
+func newFieldExtractor(collector *[]domain.FieldDefinition) *fieldExtractor {
+	return &fieldExtractor{collector: collector}
+}
+
+// collect applies options to def, records it and returns a placeholder.
+func (e *fieldExtractor) collect(def domain.FieldDefinition, opts []string) (string, error) {
+	if meta, ok := e.fieldMeta[def.Name]; ok {
+		opts = append(fieldMetaOptions(meta), opts...)
+	}
+	if err := applyFieldOptions(&def, opts); err != nil {
+		return "", err
+	}
+	if err := checkValidationType(&def); err != nil {
+		return "", err
+	}
+	if def.Type == domain.FieldInput && def.Default != "" {
+		if _, err := domain.ParseValue(def.ValidationType, def.Default); err != nil {
+			return "", fmt.Errorf("field %q: invalid default %q: %w", def.Name, def.Default, err)
+		}
+	}
+	if def.Type == domain.FieldRepeatable && def.Default != "" {
+		if err := domain.ValidateList(def.ValidationType, def.Default); err != nil {
+			return "", fmt.Errorf("field %q: invalid default %q: %w", def.Name, def.Default, err)
+		}
+	}
+	def.Condition = joinConditions(e.condition, def.Condition)
+	def.DependsOn = appendUnique(e.dependsOn, def.DependsOn...)
+	def.Order = e.order
+	*e.collector = append(*e.collector, def)
+	e.order++
+	return fmt.Sprintf("__PLACEHOLDER_%s__", def.Name), nil
+}
+
+// checkValidationType reports an unknown or malformed validation type of an input or
+// inputList field, and marks inputs declared like "string(optional)" optional.
+func checkValidationType(def *domain.FieldDefinition) error {
+	if def.Type != domain.FieldInput && def.Type != domain.FieldRepeatable {
+		return nil
+	}
+	specs := []string{def.ValidationType}
+	if schema, isObject := domain.ParseObjectSchema(def.ValidationType); isObject && def.Type == domain.FieldRepeatable {
+		specs = specs[:0]
+		for _, f := range schema {
+			specs = append(specs, f.ValidationType)
+		}
+	}
+	for _, spec := range specs {
+		v, err := domain.NewValidator(spec)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", def.Name, err)
+		}
+		if def.Type == domain.FieldInput && v.Optional {
+			def.Optional = true
+		}
+	}
+	return nil
+}
+
+func (e *fieldExtractor) funcMap() template.FuncMap {
+	funcs := template.FuncMap{
+		"input": func(name, validationType string, opts ...string) (string, error) {
+			return e.collect(domain.FieldDefinition{
 				Name:           name,
 				Type:           domain.FieldInput,
 				ValidationType: validationType,
-				Order:          order,
-			})
-			order++
-			return fmt.Sprintf("__PLACEHOLDER_%s__", name)
-		},
-		"autoList": func(source string) string {
-			*collector = append(*collector, domain.FieldDefinition{
-				Name:   source,
+			}, opts)
+		},
+		"inputList": func(name, validationType string, opts ...string) (interface{}, error) {
+			placeholder, err := e.collect(domain.FieldDefinition{
+				Name:           name,
+				Type:           domain.FieldRepeatable,
+				ValidationType: validationType,
+			}, opts)
+			if err != nil {
+				return nil, err
+			}
+			return listValue(validationType, placeholder), nil
+		},
+		"autoList": func(first string, rest ...string) (string, error) {
+			name, source, opts := autoListArgs(first, rest)
+			return e.collect(domain.FieldDefinition{
+				Name:   name,
 				Type:   domain.FieldAutoList,
 				Source: source,
-				Order:  order,
-			})
-			order++
-			return fmt.Sprintf("__PLACEHOLDER_%s__", source)
+			}, opts)
 		},
-		"templateGroup": func(group string) string {
-			*collector = append(*collector, domain.FieldDefinition{
+		"templateGroup": func(group string, opts ...string) (string, error) {
+			return e.collect(domain.FieldDefinition{
 				Name:   group,
 				Type:   domain.FieldTemplateGroup,
 				Source: group,
-				Order:  order,
-			})
-			order++
-			return fmt.Sprintf("__PLACEHOLDER_%s__", group)
+			}, opts)
 		},
-		"staticList": func(listName string) string {
-			*collector = append(*collector, domain.FieldDefinition{
+		"staticList": func(listName string, opts ...string) (string, error) {
+			return e.collect(domain.FieldDefinition{
 				Name:   listName,
 				Type:   domain.FieldStaticList,
 				Source: listName,
-				Order:  order,
-			})
-			order++
-			return fmt.Sprintf("__PLACEHOLDER_%s__", listName)
+			}, opts)
+		},
+		"derive": func(name string, value interface{}, opts ...string) (string, error) {
+			return e.collect(domain.FieldDefinition{
+				Name: name,
+				Type: domain.FieldDerived,
+			}, opts)
+		},
+		"subTemplateSelected": func(group, description string) bool {
+			return false
+		},
+		"include": func(name string, data interface{}) string {
+			return ""
 		},
-		"indent": func(spaces int, content string) string {
-			return indentString(spaces, content)
+		"outputFile": func(filename string) string {
+			return ""
+		},
+		"fieldIs": func(name, value string) bool {
+			return false
 		},
 	}
+	for name, fn := range extractorHelperFuncs() {
+		funcs[name] = fn
+	}
+	for name, fn := range generatorFuncs(NewSecrets().scope()) {
+		funcs[name] = fn
+	}
+	for name, fn := range environmentFuncs(&templateFiles{}) {
+		funcs[name] = fn
+	}
+	funcs["lookup"] = lookupFunc(nil, "")
+	return funcs
+}
+
+// NewExtractorFuncMap returns a FuncMap for pass 1 (field extraction).
+// Each custom function appends a FieldDefinition to the collector and returns a placeholder.
+// Template execution is single-threaded, so no synchronization is needed.
+func NewExtractorFuncMap(collector *[]domain.FieldDefinition) template.FuncMap {
+	return newFieldExtractor(collector).funcMap()
 }
 
 // NewRendererFuncMap returns a FuncMap for pass 2 (rendering with collected values).
+// Fields without a collected value fall back to the default declared in the template.
+// The value of a templateGroup field is the chosen sub-template content, which is
+// itself rendered with the same functions before being substituted.
+// Multi-select staticList fields render as a YAML block sequence of the selected items.
 func NewRendererFuncMap(values map[string]string) template.FuncMap {
-	return template.FuncMap{
-		"input": func(name, validationType string) string {
-			if v, ok := values[name]; ok {
-				return v
-			}
-			return ""
+	lookup := func(name string, fieldType domain.FieldType, opts []string) string {
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return renderOptions(fieldType, opts).Default
+	}
+	funcs := template.FuncMap{
+		"input": func(name, validationType string, opts ...string) string {
+			return lookup(name, domain.FieldInput, opts)
 		},
-		"autoList": func(source string) string {
-			if v, ok := values[source]; ok {
-				return v
-			}
-			return ""
+		"inputList": func(name, validationType string, opts ...string) interface{} {
+			return listValue(validationType, lookup(name, domain.FieldRepeatable, opts))
 		},
-		"templateGroup": func(group string) string {
-			if v, ok := values[group]; ok {
-				return v
+		"autoList": func(first string, rest ...string) string {
+			name, _, opts := autoListArgs(first, rest)
+			return lookup(name, domain.FieldAutoList, opts)
+		},
+		"staticList": func(listName string, opts ...string) string {
+			value := lookup(listName, domain.FieldStaticList, opts)
+			if renderOptions(domain.FieldStaticList, opts).Multiple {
+				return yamlSequence(domain.SplitList(value))
 			}
-			return ""
+			return value
 		},
-		"staticList": func(listName string) string {
-			if v, ok := values[listName]; ok {
+		// derive yields the computed value unless one was collected (e.g. a flag override),
+		// and records it so later expressions can refer to it as .name.
+		"derive": func(name string, computed interface{}, opts ...string) string {
+			if v, ok := values[name]; ok && v != "" {
 				return v
 			}
-			return ""
+			v := fmt.Sprint(computed)
+			values[name] = v
+			return v
+		},
+		"subTemplateSelected": func(group, description string) bool {
+			return false
+		},
+		"include": func(name string, data interface{}) (string, error) {
+			return "", fmt.Errorf("include %q: partials require a template registry", name)
 		},
-		"indent": func(spaces int, content string) string {
-			return indentString(spaces, content)
+		"outputFile": outputFileLine,
+		// fieldIs reports whether a field's value is value, or includes it for list values.
+		"fieldIs": func(name, value string) bool {
+			return values[name] == value || slices.Contains(domain.SplitList(values[name]), value)
 		},
 	}
+	for name, fn := range helperFuncs() {
+		funcs[name] = fn
+	}
+	for name, fn := range generatorFuncs(NewSecrets().scope()) {
+		funcs[name] = fn
+	}
+	for name, fn := range environmentFuncs(&templateFiles{}) {
+		funcs[name] = fn
+	}
+	funcs["lookup"] = lookupFunc(nil, "")
+	funcs["templateGroup"] = func(group string, opts ...string) (string, error) {
+		return renderFragment(fmt.Sprintf("sub-template for %q", group), values[group], funcs, values)
+	}
+	return funcs
+}
+
+// listValue converts a repeatable field value into something templates can range over:
+// a []string for plain entries, or a []map[string]string for object entries.
+func listValue(validationType, value string) interface{} {
+	entries := domain.SplitList(value)
+	if _, isObject := domain.ParseObjectSchema(validationType); !isObject {
+		if entries == nil {
+			return []string{}
+		}
+		return entries
+	}
+	objects := make([]map[string]string, len(entries))
+	for i, entry := range entries {
+		objects[i], _ = domain.ParseObjectEntry(entry) // validated when answered
+	}
+	return objects
+}
+
+// yamlSequence renders items as a YAML block sequence, or an empty flow sequence
+// when there are none, so the result is valid YAML either way.
+func yamlSequence(items []string) string {
+	if len(items) == 0 {
+		return "[]"
+	}
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "- " + item
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderFragment renders sub-template or partial content with the given functions.
+// what describes the fragment in errors. Field values are escaped for YAML as in the main template.
+func renderFragment(what, content string, funcs template.FuncMap, data interface{}) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(what).Funcs(funcs).Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s: %w", what, err)
+	}
+	if err := foldConstants(tmpl, funcs); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", what, err)
+	}
+	keyGenerators(tmpl, "")
+	prepareYAML(tmpl)
+	out, err := executeYAML(tmpl, data)
+	if err != nil {
+		return "", fmt.Errorf("rendering %s: %w", what, err)
+	}
+	return out.buf.String(), nil
 }
 
 // indentString indents each line of content by the given number of spaces.
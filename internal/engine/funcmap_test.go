@@ -114,3 +114,178 @@ func TestIndentString(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractorFuncMapDefaults(t *testing.T) {
+	var fields []domain.FieldDefinition
+	fm := NewExtractorFuncMap(&fields)
+
+	tmplStr := `{{ input "instances" "integer" "default=3" }} {{ staticList "methods" "default=volumeSnapshot" }} {{ input "name" "dns-name" }}`
+	tmpl, err := template.New("test").Funcs(fm).Parse(tmplStr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d", len(fields))
+	}
+	if fields[0].Default != "3" {
+		t.Errorf("field[0].Default = %q, want %q", fields[0].Default, "3")
+	}
+	if fields[1].Default != "volumeSnapshot" {
+		t.Errorf("field[1].Default = %q, want %q", fields[1].Default, "volumeSnapshot")
+	}
+	if fields[2].Default != "" {
+		t.Errorf("field[2].Default = %q, want empty", fields[2].Default)
+	}
+}
+
+func TestExtractorFuncMapOptionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmplStr string
+	}{
+		{"invalid default", `{{ input "instances" "integer" "default=three" }}`},
+		{"unknown option", `{{ input "name" "dns-name" "colour=blue" }}`},
+		{"malformed option", `{{ input "name" "dns-name" "default" }}`},
+		{"show rule without field", `{{ input "name" "dns-name" "show= == x" }}`},
+		{"unknown validation type", `{{ input "name" "colour" }}`},
+		{"invalid validation arguments", `{{ input "replicas" "integer(min=9,max=1)" }}`},
+		{"unknown object validation type", `{{ inputList "ports" "name:dns-name,port:colour" }}`},
+		{"default breaking a rule", `{{ input "replicas" "integer(max=9)" "default=12" }}`},
+		{"namespace on a namespace field", `{{ autoList "target-ns" "namespace" "namespace=source-ns" }}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []domain.FieldDefinition
+			tmpl, err := template.New("test").Funcs(NewExtractorFuncMap(&fields)).Parse(tt.tmplStr)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, nil); err == nil {
+				t.Errorf("expected error for %s", tt.tmplStr)
+			}
+		})
+	}
+}
+
+func TestExtractorFuncMapOptionalValidationType(t *testing.T) {
+	var fields []domain.FieldDefinition
+	tmpl, err := template.New("test").Funcs(NewExtractorFuncMap(&fields)).Parse(`{{ input "comment" "string(optional)" }} {{ input "name" "dns-name" }}`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	if !fields[0].Optional {
+		t.Error("string(optional) field should be optional")
+	}
+	if fields[1].Optional {
+		t.Error("dns-name field should not be optional")
+	}
+}
+
+func TestRendererFuncMapDefaults(t *testing.T) {
+	fm := NewRendererFuncMap(map[string]string{"name": "mydb"})
+
+	tmplStr := `{{ input "name" "dns-name" "default=other" }} {{ input "instances" "integer" "default=3" }}`
+	tmpl, err := template.New("test").Funcs(fm).Parse(tmplStr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+
+	if got, want := buf.String(), "mydb 3"; got != want {
+		t.Errorf("rendered = %q, want %q", got, want)
+	}
+}
+
+func TestExtractorFuncMapMetadata(t *testing.T) {
+	var fields []domain.FieldDefinition
+	fm := NewExtractorFuncMap(&fields)
+
+	tmplStr := `{{ input "name" "dns-name" "label=Cluster name" "help=Name of the cluster" "placeholder=orders-db" }} {{ templateGroup "res" "label=Resources" }}`
+	tmpl, err := template.New("test").Funcs(fm).Parse(tmplStr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 fields, got %d", len(fields))
+	}
+	if fields[0].Label != "Cluster name" || fields[0].Title() != "Cluster name" {
+		t.Errorf("field[0].Label = %q, want %q", fields[0].Label, "Cluster name")
+	}
+	if fields[0].Description != "Name of the cluster" {
+		t.Errorf("field[0].Description = %q, want %q", fields[0].Description, "Name of the cluster")
+	}
+	if fields[0].Placeholder != "orders-db" {
+		t.Errorf("field[0].Placeholder = %q, want %q", fields[0].Placeholder, "orders-db")
+	}
+	if fields[1].Title() != "Resources" {
+		t.Errorf("field[1].Title() = %q, want %q", fields[1].Title(), "Resources")
+	}
+}
+
+func TestAutoListAliases(t *testing.T) {
+	var fields []domain.FieldDefinition
+	tmplStr := `{{ autoList "namespace" }} {{ autoList "source-ns" "namespace" "label=Source namespace" }} {{ autoList "target-ns" "namespace" "optional" }}`
+
+	tmpl, err := template.New("test").Funcs(NewExtractorFuncMap(&fields)).Parse(tmplStr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+
+	want := []struct{ name, source string }{
+		{"namespace", "namespace"},
+		{"source-ns", "namespace"},
+		{"target-ns", "namespace"},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("expected %d fields, got %d: %+v", len(want), len(fields), fields)
+	}
+	for i, w := range want {
+		if fields[i].Name != w.name || fields[i].Source != w.source {
+			t.Errorf("field[%d] = %s/%s, want %s/%s", i, fields[i].Name, fields[i].Source, w.name, w.source)
+		}
+	}
+	if fields[1].Label != "Source namespace" || !fields[2].Optional {
+		t.Errorf("options not applied after the source: %+v", fields[1:])
+	}
+
+	tmpl, err = template.New("test").Funcs(NewRendererFuncMap(map[string]string{
+		"namespace": "default",
+		"source-ns": "staging",
+		"target-ns": "production",
+	})).Parse(tmplStr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	buf.Reset()
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	if got, want := buf.String(), "default staging production"; got != want {
+		t.Errorf("rendered = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,481 @@
+package engine
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Character sets accepted by password by name. Any other charset argument is used
+// as the literal set of characters to draw from.
+var charsets = map[string]string{
+	"alpha":    "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"numeric":  "0123456789",
+	"alphanum": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"hex":      "0123456789abcdef",
+	"symbols":  "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#%&*+-.=?@^_~",
+}
+
+// maskedValue replaces generated secrets in previews.
+const maskedValue = "********"
+
+// minMaskedLength is the shortest generated value Mask replaces; masking shorter
+// ones would garble unrelated values equal to them.
+const minMaskedLength = 4
+
+// Secrets records the values produced by generator functions such as password and
+// genCA during a run. Each call is keyed by where it is in the template: by template
+// and position ("password@main:5:12"), or within a derived field's expression by
+// the field's name and the order of the call in it ("service.password-1"). Loading
+// the record of an earlier run makes every call return the value it returned then,
+// however other answers changed which calls ran.
+type Secrets struct {
+	values map[string]string
+}
+
+// NewSecrets creates an empty record.
+func NewSecrets() *Secrets {
+	return &Secrets{values: make(map[string]string)}
+}
+
+// Load makes the generator calls of this run return the recorded values.
+func (s *Secrets) Load(recorded map[string]string) {
+	for key, value := range recorded {
+		s.values[key] = value
+	}
+}
+
+// Recorded returns a copy of the values generated or loaded so far.
+func (s *Secrets) Recorded() map[string]string {
+	return copyValues(s.values)
+}
+
+// Mask replaces every recorded secret in text, verbatim or base64-encoded, with
+// asterisks. Multi-line values such as private keys are masked line by line, so
+// they are hidden however they were indented. Certificates are public and kept.
+// Only whole values are masked: a secret that is part of a longer name, label or
+// key, with a letter, digit, "-", "_" or "." next to it, is left alone.
+func (s *Secrets) Mask(text string) string {
+	var secrets []string
+	for key, value := range s.values {
+		if strings.HasSuffix(key, ".cert") {
+			continue
+		}
+		secrets = append(secrets, base64.StdEncoding.EncodeToString([]byte(value)))
+		secrets = append(secrets, strings.Split(value, "\n")...)
+	}
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); len(secret) >= minMaskedLength {
+			text = maskWhole(text, secret)
+		}
+	}
+	return text
+}
+
+// maskWhole replaces the occurrences of secret in text that are whole values.
+func maskWhole(text, secret string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(text, secret)
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		end := i + len(secret)
+		if (i == 0 || !isNameByte(text[i-1])) && (end == len(text) || !isNameByte(text[end])) {
+			b.WriteString(text[:i])
+			b.WriteString(maskedValue)
+		} else {
+			b.WriteString(text[:end])
+		}
+		text = text[end:]
+	}
+}
+
+// isNameByte reports whether c can be part of a name, label or key.
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+}
+
+// secretScope is one execution of a template, such as rendering it or deriving a
+// field, recording into Secrets. A call run again in the same execution, as in a
+// range loop, is told apart by a count: "password@main:5:12#2".
+type secretScope struct {
+	secrets *Secrets
+	counts  map[string]int
+}
+
+// scope starts an execution recording into s.
+func (s *Secrets) scope() *secretScope {
+	return &secretScope{secrets: s, counts: make(map[string]int)}
+}
+
+// key returns the key of this run of the call keyed key, or "" for a call that
+// is not recorded.
+func (c *secretScope) key(key string) string {
+	if key == "" {
+		return ""
+	}
+	c.counts[key]++
+	if n := c.counts[key]; n > 1 {
+		return fmt.Sprintf("%s#%d", key, n)
+	}
+	return key
+}
+
+// generate returns the recorded value of the call to fn keyed key, generating and
+// recording one if there is none.
+func (c *secretScope) generate(fn, key string, gen func() (string, error)) (string, error) {
+	return c.hash(fn, key, func(string) bool { return true }, gen)
+}
+
+// hash is like generate for salted hashes of their arguments. A recorded hash
+// is only returned while matches holds for it, so changed arguments hash anew.
+func (c *secretScope) hash(fn, key string, matches func(string) bool, gen func() (string, error)) (string, error) {
+	key = c.key(key)
+	if v, ok := c.secrets.values[key]; ok && key != "" && matches(v) {
+		return v, nil
+	}
+	v, err := gen()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+	if key != "" {
+		c.secrets.values[key] = v
+	}
+	return v, nil
+}
+
+// certificate is like generate for functions returning a certificate, recording
+// its PEM certificate and key as "<key>.cert" and "<key>.key".
+func (c *secretScope) certificate(fn, key string, gen func() (certificate, error)) (certificate, error) {
+	key = c.key(key)
+	cert, hasCert := c.secrets.values[key+".cert"]
+	privateKey, hasKey := c.secrets.values[key+".key"]
+	if key != "" && hasCert && hasKey {
+		return certificate{Cert: cert, Key: privateKey}, nil
+	}
+	generated, err := gen()
+	if err != nil {
+		return certificate{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	if key != "" {
+		c.secrets.values[key+".cert"] = generated.Cert
+		c.secrets.values[key+".key"] = generated.Key
+	}
+	return generated, nil
+}
+
+// certificate is a PEM-encoded certificate and its private key, as returned by
+// genCA, genSelfSignedCert and genSignedCert and used as {{ $ca.Cert }} / {{ $ca.Key }}.
+type certificate struct {
+	Cert string
+	Key  string
+}
+
+// keyedSuffix names the form of a generator function taking the key of the call
+// first, which keyGenerators rewrites calls to.
+const keyedSuffix = "Keyed"
+
+// generatorFuncs returns the functions generating random values and credentials.
+// Names and arguments follow Helm/Sprig. Calls rewritten by keyGenerators record
+// their values in c; calls that are not, as in field conditions, are not recorded.
+func generatorFuncs(c *secretScope) template.FuncMap {
+	funcs := template.FuncMap{}
+	for name, fn := range keyedGeneratorFuncs(c) {
+		funcs[name+keyedSuffix] = fn
+		funcs[name] = unkeyed(fn)
+	}
+	return funcs
+}
+
+func keyedGeneratorFuncs(c *secretScope) template.FuncMap {
+	randFunc := func(fn, charset string) func(string, int) (string, error) {
+		return func(key string, length int) (string, error) {
+			return c.generate(fn, key, func() (string, error) { return randomString(length, charset) })
+		}
+	}
+	return template.FuncMap{
+		"randAlphaNum": randFunc("randAlphaNum", charsets["alphanum"]),
+		"randAlpha":    randFunc("randAlpha", charsets["alpha"]),
+		"randNumeric":  randFunc("randNumeric", charsets["numeric"]),
+		"password": func(key string, length int, charset ...string) (string, error) {
+			chars := charsets["alphanum"]
+			if len(charset) > 0 {
+				chars = charset[0]
+				if named, ok := charsets[chars]; ok {
+					chars = named
+				}
+			}
+			return c.generate("password", key, func() (string, error) { return randomString(length, chars) })
+		},
+		"uuid": func(key string) (string, error) {
+			return c.generate("uuid", key, func() (string, error) { return uuid.NewString(), nil })
+		},
+		"htpasswd": func(key, user, password string) (string, error) {
+			return c.hash("htpasswd", key, func(line string) bool { return htpasswdMatches(line, user, password) }, func() (string, error) {
+				return htpasswd(user, password)
+			})
+		},
+		"genCA": func(key, cn string, days int) (certificate, error) {
+			return c.certificate("genCA", key, func() (certificate, error) {
+				return generateCertificate(cn, nil, nil, days, true, nil)
+			})
+		},
+		"genSelfSignedCert": func(key, cn string, ips, alternateNames []interface{}, days int) (certificate, error) {
+			return c.certificate("genSelfSignedCert", key, func() (certificate, error) {
+				return generateCertificate(cn, ips, alternateNames, days, false, nil)
+			})
+		},
+		"genSignedCert": func(key, cn string, ips, alternateNames []interface{}, days int, ca certificate) (certificate, error) {
+			return c.certificate("genSignedCert", key, func() (certificate, error) {
+				return generateCertificate(cn, ips, alternateNames, days, false, &ca)
+			})
+		},
+	}
+}
+
+// unkeyed returns fn, a keyed generator function, without its key parameter,
+// for calls that are not recorded.
+func unkeyed(fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	in := make([]reflect.Type, t.NumIn()-1)
+	for i := range in {
+		in[i] = t.In(i + 1)
+	}
+	out := make([]reflect.Type, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i)
+	}
+	noKey := reflect.ValueOf("")
+	return reflect.MakeFunc(reflect.FuncOf(in, out, t.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		args = append([]reflect.Value{noKey}, args...)
+		if t.IsVariadic() {
+			return v.CallSlice(args)
+		}
+		return v.Call(args)
+	}).Interface()
+}
+
+// keyGenerators rewrites the generator calls in every template associated with
+// tmpl to their keyed forms, keyed by position, or, within the expression of a
+// derived field or when derive names one, by the field and the order of the call.
+func keyGenerators(tmpl *template.Template, derive string) {
+	generators := keyedGeneratorFuncs(nil)
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		k := &generatorKeys{tree: t.Tree, generators: generators, derive: derive}
+		k.node(t.Tree.Root)
+	}
+}
+
+type generatorKeys struct {
+	tree       *parse.Tree
+	generators template.FuncMap
+	derive     string // derived field whose expression is being rewritten
+	calls      int    // generator calls in it so far
+}
+
+func (k *generatorKeys) node(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			k.node(child)
+		}
+	case *parse.ActionNode:
+		k.pipe(n.Pipe)
+	case *parse.IfNode:
+		k.branch(&n.BranchNode)
+	case *parse.RangeNode:
+		k.branch(&n.BranchNode)
+	case *parse.WithNode:
+		k.branch(&n.BranchNode)
+	case *parse.TemplateNode:
+		k.pipe(n.Pipe)
+	}
+}
+
+func (k *generatorKeys) branch(b *parse.BranchNode) {
+	k.pipe(b.Pipe)
+	k.node(b.List)
+	k.node(b.ElseList)
+}
+
+func (k *generatorKeys) pipe(pipe *parse.PipeNode) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		k.command(cmd)
+	}
+}
+
+func (k *generatorKeys) command(cmd *parse.CommandNode) {
+	ident, isIdent := cmd.Args[0].(*parse.IdentifierNode)
+	if isIdent && ident.Ident == "derive" && len(cmd.Args) > 2 {
+		if name, ok := cmd.Args[1].(*parse.StringNode); ok {
+			outer, outerCalls := k.derive, k.calls
+			k.derive, k.calls = name.Text, 0
+			defer func() { k.derive, k.calls = outer, outerCalls }()
+		}
+	}
+	for _, arg := range cmd.Args {
+		switch a := arg.(type) {
+		case *parse.PipeNode:
+			k.pipe(a)
+		case *parse.ChainNode:
+			if nested, ok := a.Node.(*parse.PipeNode); ok {
+				k.pipe(nested)
+			}
+		}
+	}
+	if !isIdent || k.generators[ident.Ident] == nil {
+		return
+	}
+	var key string
+	if k.derive != "" {
+		k.calls++
+		key = fmt.Sprintf("%s.%s-%d", k.derive, ident.Ident, k.calls)
+	} else {
+		location, _ := k.tree.ErrorContext(cmd)
+		key = ident.Ident + "@" + location
+	}
+	keyNode := &parse.StringNode{NodeType: parse.NodeString, Pos: ident.Pos, Quoted: strconv.Quote(key), Text: key}
+	ident.Ident += keyedSuffix
+	cmd.Args = append([]parse.Node{ident, keyNode}, cmd.Args[1:]...)
+}
+
+// randomString returns length characters drawn uniformly from charset.
+func randomString(length int, charset string) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("length must be positive, got %d", length)
+	}
+	chars := []rune(charset)
+	if len(chars) == 0 {
+		return "", fmt.Errorf("empty character set")
+	}
+	result := make([]rune, length)
+	for i := range result {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = chars[n.Int64()]
+	}
+	return string(result), nil
+}
+
+// htpasswd returns an htpasswd line for user with a bcrypt password hash, as
+// Helm's htpasswd does, which nginx and Apache accept for basic auth.
+func htpasswd(user, password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return user + ":" + string(hash), nil
+}
+
+// htpasswdMatches reports whether line is an htpasswd line for user and password.
+func htpasswdMatches(line, user, password string) bool {
+	lineUser, hash, ok := strings.Cut(line, ":")
+	return ok && lineUser == user && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// generateCertificate creates an RSA key and a certificate for cn valid for days,
+// either self-signed or signed by ca. A CA certificate may sign other certificates.
+func generateCertificate(cn string, ips, alternateNames []interface{}, days int, isCA bool, ca *certificate) (certificate, error) {
+	if days <= 0 {
+		return certificate{}, fmt.Errorf("validity must be a positive number of days, got %d", days)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return certificate{}, fmt.Errorf("generating key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return certificate{}, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Duration(days) * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	for _, ip := range ips {
+		parsed := net.ParseIP(toString(ip))
+		if parsed == nil {
+			return certificate{}, fmt.Errorf("invalid IP address %q", toString(ip))
+		}
+		tmpl.IPAddresses = append(tmpl.IPAddresses, parsed)
+	}
+	for _, name := range alternateNames {
+		tmpl.DNSNames = append(tmpl.DNSNames, toString(name))
+	}
+
+	parent, signer := tmpl, interface{}(key)
+	if ca != nil {
+		parent, signer, err = parseCertificate(*ca)
+		if err != nil {
+			return certificate{}, err
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		return certificate{}, fmt.Errorf("creating certificate: %w", err)
+	}
+	return certificate{
+		Cert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Key:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+	}, nil
+}
+
+// parseCertificate decodes a PEM certificate and its RSA key, as produced by genCA.
+func parseCertificate(c certificate) (*x509.Certificate, *rsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode([]byte(c.Cert))
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("CA certificate is not PEM-encoded")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing CA certificate: %w", err)
+	}
+	keyBlock, _ := pem.Decode([]byte(c.Key))
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("CA key is not PEM-encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing CA key: %w", err)
+	}
+	return cert, key, nil
+}
@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// helperFuncs returns the general-purpose template helpers shared by the extractor
+// and renderer func maps. Names and argument order follow Helm/Sprig, with the piped
+// value last, so {{ input "name" "dns-name" | quote }} and {{ .x | default "y" }} work.
+func helperFuncs() template.FuncMap {
+	return template.FuncMap{
+		// Strings
+		"quote":      quote,
+		"squote":     squote,
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"trunc":      trunc,
+		"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"toString":   toString,
+		"raw":        func(v interface{}) interface{} { return v },
+
+		// Defaults and checks
+		"default":  defaultValue,
+		"required": required,
+		"empty":    isEmpty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+
+		// Encoding
+		"toYaml":    toYaml,
+		"toJson":    toJSON,
+		"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":    b64dec,
+		"sha256sum": sha256sum,
+
+		// Layout
+		"indent":  indentString,
+		"nindent": func(spaces int, content string) string { return "\n" + indentString(spaces, content) },
+
+		// Collections
+		"list":      func(items ...interface{}) []interface{} { return items },
+		"dict":      dict,
+		"join":      join,
+		"splitList": func(sep, s string) []string { return strings.Split(s, sep) },
+	}
+}
+
+// extractorHelperFuncs returns helperFuncs with checks that would reject placeholder
+// values relaxed, so executing a template with the extractor func map never fails
+// because a field has no real value yet.
+func extractorHelperFuncs() template.FuncMap {
+	funcs := helperFuncs()
+	funcs["required"] = func(msg string, v interface{}) interface{} {
+		return v
+	}
+	funcs["b64dec"] = func(s string) string {
+		if decoded, err := b64dec(s); err == nil {
+			return decoded
+		}
+		return s
+	}
+	return funcs
+}
+
+// quote wraps each non-nil argument in double quotes, escaping as needed,
+// and joins them with spaces.
+func quote(values ...interface{}) string {
+	var quoted []string
+	for _, v := range values {
+		if v != nil {
+			quoted = append(quoted, fmt.Sprintf("%q", toString(v)))
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// squote wraps each non-nil argument in single quotes. Embedded single quotes are
+// doubled, which is how YAML escapes them inside a single-quoted scalar.
+func squote(values ...interface{}) string {
+	var quoted []string
+	for _, v := range values {
+		if v != nil {
+			quoted = append(quoted, "'"+strings.ReplaceAll(toString(v), "'", "''")+"'")
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// trunc truncates s to length characters; a negative length keeps the last characters instead.
+func trunc(length int, s string) string {
+	runes := []rune(s)
+	if length < 0 && len(runes)+length > 0 {
+		return string(runes[len(runes)+length:])
+	}
+	if length >= 0 && len(runes) > length {
+		return string(runes[:length])
+	}
+	return s
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isEmpty reports whether v is nil or the zero value of its kind, with empty
+// strings, slices and maps counting as empty.
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Array, reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+// defaultValue returns given unless it is empty, in which case def is returned.
+// A missing piped value (e.g. {{ default "x" }}) also yields def.
+func defaultValue(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || isEmpty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+// required fails rendering with msg when v is nil or an empty string.
+func required(msg string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, fmt.Errorf("%s", msg)
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return nil, fmt.Errorf("%s", msg)
+	}
+	return v, nil
+}
+
+// coalesce returns the first non-empty argument, or nil if all are empty.
+func coalesce(values ...interface{}) interface{} {
+	for _, v := range values {
+		if !isEmpty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+func ternary(ifTrue, ifFalse interface{}, cond bool) interface{} {
+	if cond {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+// toYaml marshals v as YAML without the trailing newline, ready for nindent.
+func toYaml(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return string(data), nil
+}
+
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(data), nil
+}
+
+func sha256sum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// dict builds a map from alternating string keys and values.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected key/value pairs, got %d arguments", len(pairs))
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// join joins the elements of a list with sep. A single string is returned as-is.
+func join(sep string, v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return toString(v)
+	}
+	items := make([]string, rv.Len())
+	for i := range items {
+		items[i] = toString(rv.Index(i).Interface())
+	}
+	return strings.Join(items, sep)
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+
+	"inscribe/internal/domain"
+)
+
+func TestHelperFuncs(t *testing.T) {
+	values := map[string]string{
+		"name":  "mydb",
+		"empty": "",
+		"quote": `say "hi"`,
+		"apos":  "it's",
+	}
+
+	tests := []struct {
+		name    string
+		tmplStr string
+		want    string
+	}{
+		{"quote", `{{ input "name" "dns-name" | quote }}`, `"mydb"`},
+		{"quote escapes", `{{ .quote | quote }}`, `"say \"hi\""`},
+		{"squote", `{{ .name | squote }}`, `'mydb'`},
+		{"squote escapes", `{{ .apos | squote }}`, `'it''s'`},
+		{"upper", `{{ .name | upper }}`, "MYDB"},
+		{"lower", `{{ "MyDB" | lower }}`, "mydb"},
+		{"trim", `{{ "  x  " | trim }}`, "x"},
+		{"trimPrefix", `{{ "pg-main" | trimPrefix "pg-" }}`, "main"},
+		{"trimSuffix", `{{ "main-rw" | trimSuffix "-rw" }}`, "main"},
+		{"trunc", `{{ "abcdef" | trunc 3 }}`, "abc"},
+		{"trunc negative", `{{ "abcdef" | trunc -2 }}`, "ef"},
+		{"trunc short", `{{ "ab" | trunc 5 }}`, "ab"},
+		{"replace", `{{ "a.b.c" | replace "." "-" }}`, "a-b-c"},
+		{"repeat", `{{ "ab" | repeat 2 }}`, "abab"},
+		{"contains", `{{ contains "db" .name }}`, "true"},
+		{"hasPrefix", `{{ hasPrefix "my" .name }}`, "true"},
+		{"hasSuffix", `{{ hasSuffix "x" .name }}`, "false"},
+		{"toString", `{{ toString 3 }}`, "3"},
+		{"default on empty", `{{ .empty | default "fallback" }}`, "fallback"},
+		{"default on missing", `{{ .missing | default "fallback" }}`, "fallback"},
+		{"default on value", `{{ .name | default "fallback" }}`, "mydb"},
+		{"empty", `{{ empty .empty }} {{ empty .name }}`, "true false"},
+		{"coalesce", `{{ coalesce .empty "" "second" }}`, "second"},
+		{"ternary", `{{ ternary "yes" "no" (eq .name "mydb") }}`, "yes"},
+		{"required with value", `{{ .name | required "name is required" }}`, "mydb"},
+		{"toYaml", `{{ dict "b" 2 "a" "x" | toYaml }}`, "a: x\nb: 2"},
+		{"toYaml list", `{{ list "a" "b" | toYaml }}`, "- a\n- b"},
+		{"toJson", `{{ dict "a" (list 1 2) | toJson }}`, `{"a":[1,2]}`},
+		{"b64enc", `{{ .name | b64enc }}`, "bXlkYg=="},
+		{"b64dec", `{{ "bXlkYg==" | b64dec }}`, "mydb"},
+		{"sha256sum", `{{ "abc" | sha256sum }}`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"indent", `{{ "a\nb" | indent 2 }}`, "  a\n  b"},
+		{"nindent", `x:{{ "a: 1" | nindent 2 }}`, "x:\n  a: 1"},
+		{"join", `{{ list "a" "b" | join "," }}`, "a,b"},
+		{"join inputList", `{{ inputList "roles" "dns-name" | join " " }}`, ""},
+		{"splitList", `{{ splitList "," "a,b" | join "|" }}`, "a|b"},
+		{"dict index", `{{ (dict "k" "v").k }}`, "v"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(NewRendererFuncMap(copyValues(values))).Parse(tt.tmplStr)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, values); err != nil {
+				t.Fatalf("execute error: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHelperFuncsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmplStr string
+		wantErr string
+	}{
+		{"required empty", `{{ input "name" "dns-name" | required "name is required" }}`, "name is required"},
+		{"dict odd arguments", `{{ dict "a" }}`, "key/value pairs"},
+		{"dict non-string key", `{{ dict 1 "a" }}`, "not a string"},
+		{"b64dec invalid", `{{ "%%%" | b64dec }}`, "b64dec"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(NewRendererFuncMap(map[string]string{"name": ""})).Parse(tt.tmplStr)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			var buf bytes.Buffer
+			err = tmpl.Execute(&buf, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtractorHelperFuncsPlaceholderSafe(t *testing.T) {
+	var fields []domain.FieldDefinition
+	tmplStr := `name: {{ input "name" "dns-name" | required "name is required" | quote }}
+secret: {{ input "secret" "string" | b64dec }}
+labels:{{ dict "app" (input "app" "dns-name") | toYaml | nindent 2 }}`
+
+	tmpl, err := template.New("test").Funcs(NewExtractorFuncMap(&fields)).Parse(tmplStr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatalf("execute error: %v", err)
+	}
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d: %+v", len(fields), fields)
+	}
+}
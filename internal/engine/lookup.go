@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"fmt"
+
+	"inscribe/internal/domain"
+)
+
+// lookupFunc returns Helm's lookup function, reading objects from the cluster of the
+// given context through client. Like Helm, it returns an empty map when the object
+// does not exist and, with a nil client, when rendering offline, so templates can
+// test the result with if. Each object is read once per function, so a render asks
+// the cluster once however often its templates look an object up.
+func lookupFunc(client domain.KubeClient, context string) func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	found := make(map[[4]string]map[string]interface{})
+	return func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+		if client == nil {
+			return map[string]interface{}{}, nil
+		}
+		key := [4]string{apiVersion, kind, namespace, name}
+		if obj, ok := found[key]; ok {
+			return obj, nil
+		}
+		obj, err := client.Lookup(context, apiVersion, kind, namespace, name)
+		if err != nil {
+			return nil, fmt.Errorf("lookup %s %s %q: %w", apiVersion, kind, name, err)
+		}
+		if obj == nil {
+			obj = map[string]interface{}{}
+		}
+		found[key] = obj
+		return obj, nil
+	}
+}
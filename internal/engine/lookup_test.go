@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"inscribe/internal/kubernetes"
+)
+
+// failingClient is a cluster that cannot be reached.
+type failingClient struct {
+	*kubernetes.MockClient
+}
+
+func (failingClient) Lookup(context, apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestParserRenderLookup(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "restore.yaml"),
+		`{{/* inscribe: type="template" name="restore" command="restore cnpg" */}}
+{{- $source := lookup "postgresql.cnpg.io/v1" "Cluster" "app-prod" (input "source" "dns-name") -}}
+storageClass: {{ if $source }}{{ $source.spec.storage.storageClass }}{{ else }}standard{{ end }}
+{{- $secrets := lookup "v1" "Secret" "app-prod" "" }}
+secrets: {{ len $secrets.items }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   string
+	}{
+		{
+			name:   "existing object",
+			values: map[string]string{"context": "production", "source": "main-db"},
+			want:   "storageClass: fast-ssd\nsecrets: 1\n",
+		},
+		{
+			name:   "missing object",
+			values: map[string]string{"context": "staging", "source": "main-db"},
+			want:   "storageClass: standard\nsecrets: 0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser(reg)
+			parser.SetKubeClient(kubernetes.NewMockClient())
+			got, err := parser.Render("restore", tt.values)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("offline", func(t *testing.T) {
+		// Without a client every lookup is empty, so a list has no items.
+		writeFile(t, filepath.Join(dir, "offline.yaml"),
+			`{{/* inscribe: type="template" name="offline" command="offline cnpg" */}}
+storageClass: {{ (lookup "postgresql.cnpg.io/v1" "Cluster" "app-prod" "main-db").spec | default "standard" }}
+found: {{ empty (lookup "v1" "Secret" "app-prod" "") }}
+`)
+		reg, err := NewRegistry(dir)
+		if err != nil {
+			t.Fatalf("NewRegistry() error: %v", err)
+		}
+		got, err := NewParser(reg).Render("offline", map[string]string{"context": "production"})
+		if err != nil {
+			t.Fatalf("Render() error: %v", err)
+		}
+		if want := "storageClass: standard\nfound: true\n"; got != want {
+			t.Errorf("Render() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("unreachable cluster", func(t *testing.T) {
+		parser := NewParser(reg)
+		parser.SetKubeClient(failingClient{kubernetes.NewMockClient()})
+		_, err := parser.Render("restore", map[string]string{"context": "production", "source": "main-db"})
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("expected lookup error, got %v", err)
+		}
+	})
+}
+
+// countingClient counts the lookups that reach the cluster.
+type countingClient struct {
+	*kubernetes.MockClient
+	lookups int
+}
+
+func (c *countingClient) Lookup(context, apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	c.lookups++
+	return c.MockClient.Lookup(context, apiVersion, kind, namespace, name)
+}
+
+func TestParserRenderLookupOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "restore.yaml"),
+		`{{/* inscribe: type="template" name="restore" command="restore cnpg" */}}
+{{- define "class" }}{{ (lookup "postgresql.cnpg.io/v1" "Cluster" "app-prod" "main-db").spec.storage.storageClass }}{{ end -}}
+a: {{ template "class" }}
+b: {{ template "class" }}
+missing: {{ empty (lookup "v1" "Secret" "app-prod" "none") }}{{ empty (lookup "v1" "Secret" "app-prod" "none") }}
+`)
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+
+	client := &countingClient{MockClient: kubernetes.NewMockClient()}
+	parser := NewParser(reg)
+	parser.SetKubeClient(client)
+	values := map[string]string{"context": "production"}
+	got, err := parser.Render("restore", values)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if want := "a: fast-ssd\nb: fast-ssd\nmissing: truetrue\n"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+	if client.lookups != 2 {
+		t.Errorf("render looked up %d objects, want each of 2 once", client.lookups)
+	}
+
+	if _, err := parser.Render("restore", values); err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if client.lookups != 4 {
+		t.Errorf("a second render looked up %d objects in all, want 4: each render reads the cluster anew", client.lookups)
+	}
+}
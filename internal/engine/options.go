@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"inscribe/internal/domain"
+)
+
+// applyFieldOptions parses trailing "key=value" arguments passed to a field function
+// (e.g. {{ input "instances" "integer" "default=3" "label=Instances" }}) onto the field definition.
+func applyFieldOptions(def *domain.FieldDefinition, opts []string) error {
+	for _, opt := range opts {
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok && opt == "optional" {
+			def.Optional = true
+			continue
+		}
+		// raw only changes how the value is written; see yamlsafe.go.
+		if !ok && opt == rawOption {
+			continue
+		}
+		if !ok && opt == "multi" {
+			if def.Type != domain.FieldStaticList && def.Type != domain.FieldTemplateGroup {
+				return fmt.Errorf("field %q: multi is only supported for staticList and templateGroup", def.Name)
+			}
+			def.Multiple = true
+			continue
+		}
+		if !ok {
+			return fmt.Errorf("field %q: option %q must be in key=value form", def.Name, opt)
+		}
+		switch strings.TrimSpace(key) {
+		case "default":
+			def.Default = value
+		case "label":
+			def.Label = value
+		case "help":
+			def.Description = value
+		case "placeholder":
+			def.Placeholder = value
+		case "page":
+			def.Page = value
+		case "namespace":
+			if def.Type != domain.FieldAutoList || def.Source != "cnpg-clusters" {
+				return fmt.Errorf("field %q: namespace is only supported for cnpg-clusters autoList fields", def.Name)
+			}
+			def.Namespace = value
+		case "show":
+			cond, deps, err := compileShowRule(value)
+			if err != nil {
+				return fmt.Errorf("field %q: %w", def.Name, err)
+			}
+			def.Condition = joinConditions(def.Condition, cond)
+			def.DependsOn = appendUnique(def.DependsOn, deps...)
+		default:
+			return fmt.Errorf("field %q: unknown option %q", def.Name, key)
+		}
+	}
+	// Multi-select defaults are written comma-separated but stored like any list value.
+	if def.Multiple && def.Default != "" {
+		def.Default = domain.JoinList(strings.Split(def.Default, ","))
+	}
+	return nil
+}
+
+// isFieldOption reports whether a trailing field function argument is an option
+// rather than a positional argument.
+func isFieldOption(arg string) bool {
+	return strings.Contains(arg, "=") || arg == "optional" || arg == "multi" || arg == rawOption
+}
+
+// autoListArgs splits the arguments of autoList. With a single positional argument
+// (autoList "namespace") the source doubles as the field name; with two
+// (autoList "target-ns" "namespace") the first names the field and the second is
+// the source, so a template can ask for several values of the same source.
+func autoListArgs(first string, rest []string) (name, source string, opts []string) {
+	if len(rest) > 0 && !isFieldOption(rest[0]) {
+		return first, rest[0], rest[1:]
+	}
+	return first, first, rest
+}
+
+// fieldMetaOptions returns header field metadata as field options, so it is applied
+// and validated like options given to the field function.
+func fieldMetaOptions(meta domain.FieldMeta) []string {
+	var opts []string
+	if meta.Label != "" {
+		opts = append(opts, "label="+meta.Label)
+	}
+	if meta.Help != "" {
+		opts = append(opts, "help="+meta.Help)
+	}
+	if meta.Placeholder != "" {
+		opts = append(opts, "placeholder="+meta.Placeholder)
+	}
+	if meta.Default != "" {
+		opts = append(opts, "default="+meta.Default)
+	}
+	if meta.Optional {
+		opts = append(opts, "optional")
+	}
+	if meta.Page != "" {
+		opts = append(opts, "page="+meta.Page)
+	}
+	return opts
+}
+
+// renderOptions applies opts to a definition of the given type, ignoring malformed options.
+// Used at render time, where options have already been validated by the extraction pass.
+func renderOptions(fieldType domain.FieldType, opts []string) domain.FieldDefinition {
+	def := domain.FieldDefinition{Type: fieldType}
+	_ = applyFieldOptions(&def, opts)
+	return def
+}
+
+// compileShowRule compiles a visibility rule such as "backup-methods == barmanObjectStore"
+// into a condition evaluated like those of if blocks. A rule is one or more clauses
+// joined by "&&", each of the form "field == value", "field != value", "field"
+// (answered) or "!field" (not answered). It also returns the fields the rule refers to.
+func compileShowRule(rule string) (string, []string, error) {
+	var cond string
+	var deps []string
+	for _, clause := range strings.Split(rule, "&&") {
+		clause = strings.TrimSpace(clause)
+		var field, c string
+		if name, value, ok := strings.Cut(clause, "!="); ok {
+			field = strings.TrimSpace(name)
+			c = fmt.Sprintf("not (fieldIs %q %q)", field, strings.TrimSpace(value))
+		} else if name, value, ok := strings.Cut(clause, "=="); ok {
+			field = strings.TrimSpace(name)
+			c = fmt.Sprintf("fieldIs %q %q", field, strings.TrimSpace(value))
+		} else if name, ok := strings.CutPrefix(clause, "!"); ok {
+			field = strings.TrimSpace(name)
+			c = fmt.Sprintf("not (index . %q)", field)
+		} else {
+			field = clause
+			c = fmt.Sprintf("index . %q", field)
+		}
+		if field == "" {
+			return "", nil, fmt.Errorf("invalid show rule %q: missing field name", rule)
+		}
+		cond = joinConditions(cond, c)
+		deps = appendUnique(deps, field)
+	}
+	return cond, deps, nil
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"inscribe/internal/domain"
 )
@@ -13,82 +16,445 @@ import (
 // Parser handles the two-pass template processing.
 type Parser struct {
 	registry domain.TemplateRegistry
+	secrets  *Secrets
+	kube     domain.KubeClient
 }
 
 // NewParser creates a new template parser with the given registry.
 func NewParser(registry domain.TemplateRegistry) *Parser {
-	return &Parser{registry: registry}
+	return &Parser{registry: registry, secrets: NewSecrets()}
+}
+
+// Secrets returns the record of values generated while deriving and rendering.
+// Loading an earlier run's record into it before rendering reproduces that run.
+func (p *Parser) Secrets() *Secrets {
+	return p.secrets
+}
+
+// SetKubeClient makes lookup read objects through client, from the context given by
+// the "context" value. Without a client, lookup finds nothing, as when rendering offline.
+func (p *Parser) SetKubeClient(client domain.KubeClient) {
+	p.kube = client
+}
+
+// mergeFieldMeta returns base with every attribute set in override replaced.
+func mergeFieldMeta(base, override domain.FieldMeta) domain.FieldMeta {
+	if override.Label != "" {
+		base.Label = override.Label
+	}
+	if override.Help != "" {
+		base.Help = override.Help
+	}
+	if override.Placeholder != "" {
+		base.Placeholder = override.Placeholder
+	}
+	if override.Default != "" {
+		base.Default = override.Default
+	}
+	if override.Page != "" {
+		base.Page = override.Page
+	}
+	base.Optional = base.Optional || override.Optional
+	return base
 }
 
 // ExtractFields performs pass 1: parses the template and extracts all FieldDefinitions.
 func (p *Parser) ExtractFields(templateName string) ([]domain.FieldDefinition, error) {
-	meta, err := p.registry.GetTemplate(templateName)
+	chain, err := p.templateChain(templateName)
 	if err != nil {
 		return nil, err
 	}
 
-	content, err := os.ReadFile(meta.FilePath)
+	var fields []domain.FieldDefinition
+	extractor := newFieldExtractor(&fields)
+	// Field metadata from the header of an extending template overrides its base's,
+	// one attribute at a time.
+	extractor.fieldMeta = make(map[string]domain.FieldMeta)
+	for _, meta := range chain {
+		for name, f := range meta.Fields {
+			extractor.fieldMeta[name] = mergeFieldMeta(extractor.fieldMeta[name], f)
+		}
+	}
+
+	tmpl, err := p.parseTemplate(templateName, extractor.funcMap())
 	if err != nil {
-		return nil, fmt.Errorf("reading template %q: %w", meta.FilePath, err)
+		return nil, err
 	}
 
-	// Strip the header line
-	templateContent := stripHeader(string(content))
+	// Walk the parse tree rather than executing it, so fields in every branch
+	// of a conditional block are discovered along with their conditions.
+	if err := newTreeWalker(tmpl, extractor, p.registry).extract(); err != nil {
+		return nil, fmt.Errorf("executing extraction pass for %q: %w", templateName, err)
+	}
+	if err := checkPages(fields, chain[len(chain)-1].Pages); err != nil {
+		return nil, fmt.Errorf("template %q: %w", templateName, err)
+	}
+	if err := checkNamespaces(fields); err != nil {
+		return nil, fmt.Errorf("template %q: %w", templateName, err)
+	}
+	if _, err := p.Rules(templateName, fields); err != nil {
+		return nil, err
+	}
 
-	var fields []domain.FieldDefinition
-	funcMap := NewExtractorFuncMap(&fields)
+	return fields, nil
+}
 
-	tmpl, err := template.New(templateName).Funcs(funcMap).Parse(templateContent)
+// checkPages reports a field assigned to a page the template does not declare.
+func checkPages(fields []domain.FieldDefinition, pages []domain.Page) error {
+	declared := make(map[string]bool, len(pages))
+	for _, page := range pages {
+		declared[page.Name] = true
+	}
+	for _, f := range fields {
+		if f.Page != "" && !declared[f.Page] {
+			return fmt.Errorf("field %q: unknown page %q", f.Name, f.Page)
+		}
+	}
+	return nil
+}
+
+// checkNamespaces reports a cnpg-clusters field whose namespace option does not name
+// a namespace autoList field.
+func checkNamespaces(fields []domain.FieldDefinition) error {
+	namespaces := make(map[string]bool)
+	for _, f := range fields {
+		if f.Type == domain.FieldAutoList && f.Source == "namespace" {
+			namespaces[f.Name] = true
+		}
+	}
+	for _, f := range fields {
+		if f.Namespace != "" && !namespaces[f.Namespace] {
+			return fmt.Errorf("field %q: namespace %q is not a namespace autoList field", f.Name, f.Namespace)
+		}
+	}
+	return nil
+}
+
+// parseTemplate parses the named template with funcs. A template extending another
+// is parsed on top of its base, so its define blocks replace the base's blocks of the
+// same name and the base's body, with those blocks, is what gets executed.
+func (p *Parser) parseTemplate(templateName string, funcs template.FuncMap) (*template.Template, error) {
+	chain, err := p.templateChain(templateName)
 	if err != nil {
+		return nil, err
+	}
+
+	var tmpl *template.Template
+	for _, meta := range chain {
+		content, err := os.ReadFile(meta.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("reading template %q: %w", meta.FilePath, err)
+		}
+		body := stripHeader(string(content))
+
+		if tmpl == nil {
+			tmpl, err = template.New(meta.Name).Funcs(funcs).Parse(body)
+			if err != nil {
+				return nil, fmt.Errorf("parsing template %q: %w", meta.Name, err)
+			}
+			continue
+		}
+		overrides, err := tmpl.New(meta.Name).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %q: %w", meta.Name, err)
+		}
+		if overrides.Tree != nil && !parse.IsEmptyTree(overrides.Tree.Root) {
+			return nil, fmt.Errorf("template %q extends %q: only define blocks are allowed", meta.Name, meta.Extends)
+		}
+	}
+	if err := foldConstants(tmpl, funcs); err != nil {
 		return nil, fmt.Errorf("parsing template %q: %w", templateName, err)
 	}
+	return tmpl, nil
+}
+
+// templateChain returns the named template preceded by the templates it extends,
+// base first.
+func (p *Parser) templateChain(templateName string) ([]*domain.TemplateMeta, error) {
+	var chain []*domain.TemplateMeta
+	seen := make(map[string]bool)
+	for name := templateName; name != ""; {
+		if seen[name] {
+			return nil, fmt.Errorf("template %q: inheritance cycle through %q", templateName, name)
+		}
+		seen[name] = true
+		meta, err := p.registry.GetTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]*domain.TemplateMeta{meta}, chain...)
+		name = meta.Extends
+	}
+	return chain, nil
+}
+
+// EvalCondition reports whether a field condition recorded during extraction
+// holds for the given values. An empty condition always holds.
+func (p *Parser) EvalCondition(condition string, values map[string]string) (bool, error) {
+	if condition == "" {
+		return true, nil
+	}
+
+	data := copyValues(values)
+	tmpl, err := template.New("condition").Funcs(p.rendererFuncMap(data)).Parse("{{ if " + condition + " }}true{{ end }}")
+	if err != nil {
+		return false, fmt.Errorf("parsing condition %q: %w", condition, err)
+	}
 
-	// Execute with nil data - we just want the side effects (field collection)
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, nil); err != nil {
-		return nil, fmt.Errorf("executing extraction pass for %q: %w", templateName, err)
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return false, fmt.Errorf("evaluating condition %q: %w", condition, err)
 	}
+	return buf.String() == "true", nil
+}
 
-	return fields, nil
+// Eval evaluates the expression recorded during extraction for the derived field
+// name against the given values. Values it generates are recorded under the
+// field's name, as when rendering the template.
+func (p *Parser) Eval(name, expression string, values map[string]string) (string, error) {
+	data := copyValues(values)
+	tmpl, err := template.New("expression").Funcs(p.rendererFuncMap(data)).Parse("{{ " + expression + " }}")
+	if err != nil {
+		return "", fmt.Errorf("parsing expression %q: %w", expression, err)
+	}
+	keyGenerators(tmpl, name)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("evaluating expression %q: %w", expression, err)
+	}
+	return buf.String(), nil
+}
+
+// Expand renders text, a template such as a filename pattern, against the given values.
+func (p *Parser) Expand(text string, values map[string]string) (string, error) {
+	data := copyValues(values)
+	tmpl, err := template.New("text").Funcs(p.rendererFuncMap(data)).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing %q: %w", text, err)
+	}
+	keyGenerators(tmpl, "")
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("expanding %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+// DeriveValues fills in every derived field not already present in values,
+// in template order so derived fields may build on earlier ones.
+func (p *Parser) DeriveValues(fields []domain.FieldDefinition, values map[string]string) error {
+	for _, f := range fields {
+		if f.Type != domain.FieldDerived || values[f.Name] != "" {
+			continue
+		}
+		v, err := p.Eval(f.Name, f.Expression, values)
+		if err != nil {
+			return fmt.Errorf("deriving %q: %w", f.Name, err)
+		}
+		values[f.Name] = v
+	}
+	return nil
 }
 
 // Render performs pass 2: renders the template with the given values.
 func (p *Parser) Render(templateName string, values map[string]string) (string, error) {
-	meta, err := p.registry.GetTemplate(templateName)
+	// Values are copied since derive records computed values into them, and are
+	// passed as data so expressions can refer to collected values as .name.
+	values = copyValues(values)
+
+	files, err := p.templateFiles(templateName)
 	if err != nil {
 		return "", err
 	}
-
-	content, err := os.ReadFile(meta.FilePath)
+	funcs := p.rendererFuncMap(values)
+	for name, fn := range environmentFuncs(files) {
+		funcs[name] = fn
+	}
+	tmpl, err := p.parseTemplate(templateName, funcs)
 	if err != nil {
-		return "", fmt.Errorf("reading template %q: %w", meta.FilePath, err)
+		return "", err
 	}
 
-	templateContent := stripHeader(string(content))
+	// Generated values are keyed by where they are in the template, so a replay
+	// gives each call its own value whatever else changed.
+	keyGenerators(tmpl, "")
+
+	// Field values are escaped for the YAML context they are written into, and the
+	// result is parsed so a value that still breaks the document is reported by name.
+	prepareYAML(tmpl)
+	out, err := executeYAML(tmpl, values)
+	if err != nil {
+		return "", fmt.Errorf("rendering template %q: %w", templateName, err)
+	}
+	rendered := out.buf.String()
+	if err := checkYAML(rendered, out.fields); err != nil {
+		return "", fmt.Errorf("rendering template %q: %w", templateName, err)
+	}
 
-	funcMap := NewRendererFuncMap(values)
+	return rendered, nil
+}
 
-	tmpl, err := template.New(templateName).Funcs(funcMap).Parse(templateContent)
+// templateFiles returns the resolver for file and fileGlob calls in the named
+// template: relative to its own directory first, then to those of its bases.
+func (p *Parser) templateFiles(templateName string) (*templateFiles, error) {
+	chain, err := p.templateChain(templateName)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(p.registry.Dir())
 	if err != nil {
-		return "", fmt.Errorf("parsing template %q: %w", templateName, err)
+		return nil, fmt.Errorf("resolving template directory: %w", err)
 	}
+	files := &templateFiles{root: root}
+	for i := len(chain) - 1; i >= 0; i-- {
+		dir, err := filepath.Abs(filepath.Dir(chain[i].FilePath))
+		if err != nil {
+			return nil, fmt.Errorf("resolving template directory: %w", err)
+		}
+		if !slices.Contains(files.dirs, dir) {
+			files.dirs = append(files.dirs, dir)
+		}
+	}
+	return files, nil
+}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, nil); err != nil {
-		return "", fmt.Errorf("rendering template %q: %w", templateName, err)
+// rendererFuncMap extends NewRendererFuncMap with functions that need the registry
+// or the cluster.
+// Multi-select templateGroup values hold sub-template descriptions, which are
+// resolved here and rendered one after another. Partials are rendered with the
+// data passed to include, like Helm's include.
+func (p *Parser) rendererFuncMap(values map[string]string) template.FuncMap {
+	funcs := NewRendererFuncMap(values)
+	for name, fn := range generatorFuncs(p.secrets.scope()) {
+		funcs[name] = fn
+	}
+	funcs["lookup"] = lookupFunc(p.kube, values["context"])
+	renderGroup := funcs["templateGroup"].(func(string, ...string) (string, error))
+	// Sub-templates are named by description, so the values they generate are
+	// recorded apart from those of the group's other sub-templates.
+	funcs["templateGroup"] = func(group string, opts ...string) (string, error) {
+		multiple := renderOptions(domain.FieldTemplateGroup, opts).Multiple
+		subs, err := p.registry.GetSubTemplates(group)
+		if err != nil {
+			if !multiple {
+				return renderGroup(group, opts...)
+			}
+			return "", err
+		}
+		if !multiple {
+			for _, sub := range subs {
+				if MatchesSubTemplate(values[group], sub) {
+					return renderFragment(fmt.Sprintf("sub-template %q for %q", sub.Description, group), sub.Content, funcs, values)
+				}
+			}
+			return renderGroup(group, opts...)
+		}
+		var fragments []string
+		for _, sub := range subs {
+			if !SelectsSubTemplate(values[group], sub) {
+				continue
+			}
+			fragment, err := renderFragment(fmt.Sprintf("sub-template %q for %q", sub.Description, group), sub.Content, funcs, values)
+			if err != nil {
+				return "", err
+			}
+			fragments = append(fragments, strings.TrimSuffix(fragment, "\n"))
+		}
+		return strings.Join(fragments, "\n"), nil
+	}
+	var including []string
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		if slices.Contains(including, name) {
+			return "", fmt.Errorf("partial %q includes itself", name)
+		}
+		partial, err := p.registry.GetPartial(name)
+		if err != nil {
+			return "", err
+		}
+		including = append(including, name)
+		defer func() { including = including[:len(including)-1] }()
+		return renderFragment(fmt.Sprintf("partial %q", name), partial.Content, funcs, data)
 	}
+	// fieldIs also matches templateGroup fields by sub-template description.
+	fieldIs := funcs["fieldIs"].(func(string, string) bool)
+	funcs["fieldIs"] = func(name, value string) bool {
+		if subs, err := p.registry.GetSubTemplates(name); err == nil {
+			for _, sub := range subs {
+				if strings.EqualFold(sub.Description, value) {
+					return SelectsSubTemplate(values[name], sub)
+				}
+			}
+		}
+		return fieldIs(name, value)
+	}
+	funcs["subTemplateSelected"] = func(group, description string) bool {
+		subs, err := p.registry.GetSubTemplates(group)
+		if err != nil {
+			return false
+		}
+		for _, sub := range subs {
+			if sub.Description == description {
+				return SelectsSubTemplate(values[group], sub)
+			}
+		}
+		return false
+	}
+	return funcs
+}
 
-	return buf.String(), nil
+// AvailableItems returns the items of a static list offered for the given values:
+// those without a show rule, and those whose rule holds.
+func (p *Parser) AvailableItems(list *domain.StaticListMeta, values map[string]string) ([]string, error) {
+	var items []string
+	for i, item := range list.Items {
+		if i < len(list.ItemConditions) {
+			applies, err := p.EvalCondition(list.ItemConditions[i], values)
+			if err != nil {
+				return nil, fmt.Errorf("list %q item %q: %w", list.Name, item, err)
+			}
+			if !applies {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
 }
 
-// stripHeader removes the first line if it contains an inscribe header.
-func stripHeader(content string) string {
-	lines := strings.SplitN(content, "\n", 2)
-	if len(lines) < 2 {
-		return content
+// MatchesSubTemplate reports whether value selects sub: by description (case-insensitive),
+// exact file path, or the sub-template content itself (as stored by the wizard).
+func MatchesSubTemplate(value string, sub domain.SubTemplateMeta) bool {
+	return strings.EqualFold(value, sub.Description) || value == sub.FilePath || value == sub.Content
+}
+
+// SelectsSubTemplate reports whether a templateGroup value selects sub, either as
+// a single selection or as one entry of a multi-select value.
+func SelectsSubTemplate(value string, sub domain.SubTemplateMeta) bool {
+	if MatchesSubTemplate(value, sub) {
+		return true
+	}
+	for _, entry := range domain.SplitList(value) {
+		if strings.EqualFold(entry, sub.Description) || entry == sub.FilePath {
+			return true
+		}
 	}
-	if headerRegexp.MatchString(lines[0]) {
-		return lines[1]
+	return false
+}
+
+func copyValues(values map[string]string) map[string]string {
+	result := make(map[string]string, len(values))
+	for k, v := range values {
+		result[k] = v
+	}
+	return result
+}
+
+// stripHeader removes the inscribe header, and any comments before it, from content.
+func stripHeader(content string) string {
+	if header, body, err := parseHeader(content); err == nil && header != nil {
+		return body
 	}
 	return content
 }
@@ -122,6 +122,988 @@ data:
 	}
 }
 
+func TestParserExtractFieldsConditional(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "cond.yaml"),
+		`{{/* inscribe: type="template" name="cond" command="cond" description="Conditional" */}}
+name: {{ input "name" "dns-name" }}
+{{- if eq (staticList "backups") "enabled" }}
+retention: {{ input "retention" "string" }}
+{{- if eq (input "tier" "string") "gold" }}
+replicas: {{ input "replicas" "integer" }}
+{{- end }}
+{{- else }}
+note: {{ input "note" "string" "optional" }}
+{{- end }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("cond")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+
+	byName := make(map[string]domain.FieldDefinition)
+	var names []string
+	for _, f := range fields {
+		byName[f.Name] = f
+		names = append(names, f.Name)
+	}
+	if got, want := strings.Join(names, ","), "name,backups,retention,tier,replicas,note"; got != want {
+		t.Fatalf("field order = %s, want %s", got, want)
+	}
+
+	if byName["name"].Condition != "" || byName["backups"].Condition != "" {
+		t.Errorf("top-level fields should be unconditional: %+v", fields[:2])
+	}
+	if deps := byName["retention"].DependsOn; len(deps) != 1 || deps[0] != "backups" {
+		t.Errorf("retention.DependsOn = %v, want [backups]", deps)
+	}
+	if deps := byName["replicas"].DependsOn; len(deps) != 2 {
+		t.Errorf("replicas.DependsOn = %v, want [backups tier]", deps)
+	}
+	if !byName["note"].Optional {
+		t.Error("note should be optional")
+	}
+
+	tests := []struct {
+		field  string
+		values map[string]string
+		want   bool
+	}{
+		{"retention", map[string]string{"backups": "enabled"}, true},
+		{"retention", map[string]string{"backups": "disabled"}, false},
+		{"replicas", map[string]string{"backups": "enabled", "tier": "gold"}, true},
+		{"replicas", map[string]string{"backups": "enabled", "tier": "silver"}, false},
+		{"replicas", map[string]string{"backups": "disabled", "tier": "gold"}, false},
+		{"note", map[string]string{"backups": "disabled"}, true},
+		{"note", map[string]string{"backups": "enabled"}, false},
+	}
+	for _, tt := range tests {
+		got, err := parser.EvalCondition(byName[tt.field].Condition, tt.values)
+		if err != nil {
+			t.Fatalf("EvalCondition(%q) error: %v", byName[tt.field].Condition, err)
+		}
+		if got != tt.want {
+			t.Errorf("EvalCondition(%s, %v) = %v, want %v", tt.field, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestParserExtractFieldsVariableCondition(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "vars.yaml"),
+		`{{/* inscribe: type="template" name="vars" command="vars" description="Variables" */}}
+{{- $b := staticList "backups" }}
+{{- if eq $b "enabled" }}
+retention: {{ input "retention" "string" }}
+{{- end }}
+{{- with $tier := input "tier" "string" }}
+{{- if eq $tier "gold" }}
+replicas: {{ input "replicas" "integer" }}
+{{- end }}
+{{- end }}
+{{- range $i, $port := inputList "ports" "port" }}
+{{- if eq $port "443" }}
+cert: {{ input "cert" "string" }}
+{{- end }}
+{{- end }}
+{{- with .labels }}
+{{- if .team }}
+team: {{ input "team" "string" }}
+{{- end }}
+{{- end }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("vars")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	byName := make(map[string]domain.FieldDefinition)
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	if deps := byName["retention"].DependsOn; len(deps) != 1 || deps[0] != "backups" {
+		t.Errorf("retention.DependsOn = %v, want [backups]", deps)
+	}
+
+	tests := []struct {
+		field  string
+		values map[string]string
+		want   bool
+	}{
+		{"retention", map[string]string{"backups": "enabled"}, true},
+		{"retention", map[string]string{"backups": "disabled"}, false},
+		{"replicas", map[string]string{"tier": "gold"}, true},
+		{"replicas", map[string]string{"tier": "silver"}, false},
+		{"replicas", map[string]string{"tier": ""}, false},
+		// Conditions on range variables or a moved dot cannot be evaluated on their own.
+		{"cert", map[string]string{"ports": "80"}, true},
+		{"team", map[string]string{"labels": "team=a"}, true},
+		{"team", map[string]string{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parser.EvalCondition(byName[tt.field].Condition, tt.values)
+		if err != nil {
+			t.Fatalf("EvalCondition(%q) error: %v", byName[tt.field].Condition, err)
+		}
+		if got != tt.want {
+			t.Errorf("EvalCondition(%s, %v) = %v, want %v (condition %q)", tt.field, tt.values, got, tt.want, byName[tt.field].Condition)
+		}
+	}
+}
+
+func TestParserExtractFieldsConstantArgument(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+name: {{ input (printf "na%s" "me") "dns-name" (print "default=" "app") }}
+instances: {{ input "instances" (lower "INTEGER") (printf "default=%d" 3) }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	fields, err := NewParser(reg).ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 fields, got %d: %+v", len(fields), fields)
+	}
+	if fields[0].Name != "name" || fields[0].ValidationType != "dns-name" || fields[0].Default != "app" {
+		t.Errorf("fields[0] = %+v, want name dns-name with default app", fields[0])
+	}
+	if fields[1].Name != "instances" || fields[1].ValidationType != "integer" || fields[1].Default != "3" {
+		t.Errorf("fields[1] = %+v, want instances integer with default 3", fields[1])
+	}
+
+	out, err := NewParser(reg).Render("main", map[string]string{"name": "db", "instances": "2"})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if want := "name: db\ninstances: 2\n"; out != want {
+		t.Errorf("Render() = %q, want %q", out, want)
+	}
+}
+
+func TestParserExtractFieldsNonLiteralArgument(t *testing.T) {
+	tests := []struct {
+		name string
+		call string
+	}{
+		{"answer", `input "name" "dns-name" (printf "default=%s" .other)`},
+		{"variable", `input $name "dns-name"`},
+		{"field function", `input (input "other" "string") "dns-name"`},
+		{"number", `input "name" "dns-name" 3`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "bad.yaml"),
+				`{{/* inscribe: type="template" name="bad" command="bad" description="Bad" */}}
+{{ $name := "name" }}name: {{ `+tt.call+` }}
+`)
+			reg, err := NewRegistry(dir)
+			if err != nil {
+				t.Fatalf("NewRegistry() error: %v", err)
+			}
+			_, err = NewParser(reg).ExtractFields("bad")
+			if err == nil || !strings.Contains(err.Error(), "must be string literals or pipelines of literals") {
+				t.Errorf("ExtractFields() error = %v, want non-literal argument error", err)
+			}
+		})
+	}
+}
+
+func TestParserSubTemplateFields(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+name: {{ input "name" "dns-name" }}
+resources:
+{{ templateGroup "res" | indent 2 }}
+`)
+	writeFile(t, filepath.Join(dir, "prod.yaml"),
+		`{{/* inscribe: type="sub-template" group="res" description="Production" */}}
+storage: {{ input "storage-size" "memory" }}`)
+	writeFile(t, filepath.Join(dir, "test.yaml"),
+		`{{/* inscribe: type="sub-template" group="res" description="Test" */}}
+storage: 1Gi`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d: %+v", len(fields), fields)
+	}
+	storage := fields[2]
+	if storage.Name != "storage-size" || storage.Type != domain.FieldInput {
+		t.Fatalf("field[2] = %+v, want storage-size/FieldInput", storage)
+	}
+	if len(storage.DependsOn) != 1 || storage.DependsOn[0] != "res" {
+		t.Errorf("storage-size.DependsOn = %v, want [res]", storage.DependsOn)
+	}
+
+	subs, err := reg.GetSubTemplates("res")
+	if err != nil {
+		t.Fatalf("GetSubTemplates() error: %v", err)
+	}
+	for _, sub := range subs {
+		values := map[string]string{"res": sub.Content}
+		applies, err := parser.EvalCondition(storage.Condition, values)
+		if err != nil {
+			t.Fatalf("EvalCondition() error: %v", err)
+		}
+		if want := sub.Description == "Production"; applies != want {
+			t.Errorf("storage-size applies with %q = %v, want %v", sub.Description, applies, want)
+		}
+		if sub.Description == "Production" {
+			values["name"] = "mydb"
+			values["storage-size"] = "20Gi"
+			result, err := parser.Render("main", values)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if !strings.Contains(result, "  storage: 20Gi") {
+				t.Errorf("result should contain rendered sub-template field, got:\n%s", result)
+			}
+		}
+	}
+}
+
+func TestParserDerivedFields(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+service: {{ derive "service" (printf "%s-rw" (input "name" "dns-name")) }}
+secret: {{ derive "secret" (printf "%s-app" .name) }}
+host: {{ .service }}.svc
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d: %+v", len(fields), fields)
+	}
+	if fields[0].Name != "name" {
+		t.Errorf("field[0].Name = %q, want name (dependencies are collected first)", fields[0].Name)
+	}
+	if fields[1].Name != "service" || fields[1].Type != domain.FieldDerived {
+		t.Errorf("field[1] = %+v, want service/FieldDerived", fields[1])
+	}
+	if fields[1].Expression != `printf "%s-rw" (input "name" "dns-name")` {
+		t.Errorf("field[1].Expression = %q", fields[1].Expression)
+	}
+
+	values := map[string]string{"name": "mydb"}
+	if err := parser.DeriveValues(fields, values); err != nil {
+		t.Fatalf("DeriveValues() error: %v", err)
+	}
+	if values["service"] != "mydb-rw" || values["secret"] != "mydb-app" {
+		t.Errorf("derived values = %v", values)
+	}
+
+	result, err := parser.Render("main", map[string]string{"name": "mydb", "secret": "custom"})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := "service: mydb-rw\nsecret: custom\nhost: mydb-rw.svc\n"
+	if result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+}
+
+func TestParserRepeatableFields(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+roles:
+{{- range inputList "roles" "dns-name" }}
+  - {{ . }}
+{{- end }}
+users:
+{{- range inputList "users" "name:dns-name,port:port" }}
+  - name: {{ .name }}
+    port: {{ .port }}
+{{- end }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 fields, got %d: %+v", len(fields), fields)
+	}
+	for _, f := range fields {
+		if f.Type != domain.FieldRepeatable {
+			t.Errorf("field %q type = %d, want FieldRepeatable", f.Name, f.Type)
+		}
+		if f.Condition != "" {
+			t.Errorf("field %q should be unconditional, got %q", f.Name, f.Condition)
+		}
+	}
+
+	result, err := parser.Render("main", map[string]string{
+		"roles": domain.JoinList([]string{"app", "readonly"}),
+		"users": domain.JoinList([]string{"name=alice,port=5432"}),
+	})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `roles:
+  - app
+  - readonly
+users:
+  - name: alice
+    port: 5432
+`
+	if result != want {
+		t.Errorf("rendered output mismatch:\ngot:\n%s\nwant:\n%s", result, want)
+	}
+}
+
+func TestParserMultiSelectFields(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+methods:
+{{ staticList "methods" "multi" "default=a" | indent 2 }}
+sidecars:
+{{ templateGroup "sidecars" "multi" | indent 2 }}
+`)
+	writeFile(t, filepath.Join(dir, "methods.yaml"),
+		`{{/* inscribe: type="list" name="methods" */}}
+- a
+- b
+- c`)
+	writeFile(t, filepath.Join(dir, "log.yaml"),
+		`{{/* inscribe: type="sub-template" group="sidecars" description="Logging" */}}
+- name: log
+  level: {{ input "log-level" "string" }}`)
+	writeFile(t, filepath.Join(dir, "metrics.yaml"),
+		`{{/* inscribe: type="sub-template" group="sidecars" description="Metrics" */}}
+- name: metrics`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d: %+v", len(fields), fields)
+	}
+	if !fields[0].Multiple || !fields[1].Multiple {
+		t.Errorf("expected methods and sidecars to be multi-select, got %+v", fields[:2])
+	}
+
+	values := map[string]string{
+		"methods":   domain.JoinList([]string{"a", "c"}),
+		"sidecars":  domain.JoinList([]string{"Logging", "Metrics"}),
+		"log-level": "debug",
+	}
+	applies, err := parser.EvalCondition(fields[2].Condition, values)
+	if err != nil {
+		t.Fatalf("EvalCondition() error: %v", err)
+	}
+	if !applies {
+		t.Error("log-level should apply when Logging is one of the selected sidecars")
+	}
+
+	result, err := parser.Render("main", values)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := "methods:\n  - a\n  - c\nsidecars:\n  - name: log\n    level: debug\n  - name: metrics\n"
+	if result != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, want)
+	}
+
+	values = map[string]string{"sidecars": "Metrics"}
+	if applies, _ := parser.EvalCondition(fields[2].Condition, values); applies {
+		t.Error("log-level should not apply when Logging is not selected")
+	}
+	result, err = parser.Render("main", values)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if !strings.Contains(result, "methods:\n  - a\n") {
+		t.Errorf("methods should fall back to its default, got:\n%s", result)
+	}
+}
+
+func TestParserMultiOnlyForLists(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "bad.yaml"),
+		`{{/* inscribe: type="template" name="bad" command="bad" description="Bad" */}}
+name: {{ input "name" "dns-name" "multi" }}`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	if _, err := NewParser(reg).ExtractFields("bad"); err == nil {
+		t.Error("expected error for multi on an input field")
+	}
+}
+
+func TestParserHelperFuncs(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+name: {{ input "name" "dns-name" | quote }}
+{{- if contains "prod" (input "name" "dns-name") }}
+tier: {{ input "tier" "string" | default "gold" | upper }}
+{{- end }}
+labels:{{ dict "app" (input "name" "dns-name") | toYaml | nindent 2 }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if len(fields) != 4 || fields[2].Name != "tier" || fields[2].Condition == "" {
+		t.Fatalf("expected conditional tier field among 4 fields, got %+v", fields)
+	}
+
+	result, err := parser.Render("main", map[string]string{"name": "prod-db", "tier": ""})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := "name: \"prod-db\"\ntier: GOLD\nlabels:\n  app: prod-db\n"
+	if result != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, want)
+	}
+}
+
+func TestParserPartials(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+metadata:
+  name: {{ input "name" "dns-name" }}
+{{ include "std-labels" . | indent 2 }}
+spec:
+{{ templateGroup "res" | indent 2 }}
+`)
+	writeFile(t, filepath.Join(dir, "labels.yaml"),
+		`{{/* inscribe: type="partial" name="std-labels" */}}
+labels:
+  app.kubernetes.io/instance: {{ .name }}
+  team: {{ input "team" "dns-name" }}`)
+	writeFile(t, filepath.Join(dir, "prod.yaml"),
+		`{{/* inscribe: type="sub-template" group="res" description="Production" */}}
+tier: prod
+{{ include "std-labels" . }}`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "name,team,res,team" {
+		t.Fatalf("fields = %s, want name,team,res,team", got)
+	}
+	if fields[3].Condition == "" {
+		t.Error("partial field included from a sub-template should carry the sub-template condition")
+	}
+
+	subs, err := reg.GetSubTemplates("res")
+	if err != nil {
+		t.Fatalf("GetSubTemplates() error: %v", err)
+	}
+	result, err := parser.Render("main", map[string]string{"name": "db", "team": "core", "res": subs[0].Content})
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	want := `metadata:
+  name: db
+  labels:
+    app.kubernetes.io/instance: db
+    team: core
+spec:
+  tier: prod
+  labels:
+    app.kubernetes.io/instance: db
+    team: core
+`
+	if result != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", result, want)
+	}
+}
+
+func TestParserPartialErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantErr string
+	}{
+		{"missing partial", `{{ include "nope" . }}`, `partial "nope" not found`},
+		{"self include", `{{ include "loop" . }}`, `partial "loop" includes itself`},
+		{"non-literal name", `{{ include .name . }}`, "must be a string literal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "main.yaml"),
+				`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}`+"\n"+tt.tmpl)
+			writeFile(t, filepath.Join(dir, "loop.yaml"),
+				`{{/* inscribe: type="partial" name="loop" */}}
+{{ include "loop" . }}`)
+			reg, err := NewRegistry(dir)
+			if err != nil {
+				t.Fatalf("NewRegistry() error: %v", err)
+			}
+			_, err = NewParser(reg).ExtractFields("main")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExtractFields() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileShowRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		wantCond string
+		wantDeps []string
+	}{
+		{"method == barmanObjectStore", `fieldIs "method" "barmanObjectStore"`, []string{"method"}},
+		{"method != volumeSnapshot", `not (fieldIs "method" "volumeSnapshot")`, []string{"method"}},
+		{"bucket", `index . "bucket"`, []string{"bucket"}},
+		{"!bucket", `not (index . "bucket")`, []string{"bucket"}},
+		{"method == a && bucket", `and (fieldIs "method" "a") (index . "bucket")`, []string{"method", "bucket"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			cond, deps, err := compileShowRule(tt.rule)
+			if err != nil {
+				t.Fatalf("compileShowRule() error: %v", err)
+			}
+			if cond != tt.wantCond {
+				t.Errorf("condition = %q, want %q", cond, tt.wantCond)
+			}
+			if strings.Join(deps, ",") != strings.Join(tt.wantDeps, ",") {
+				t.Errorf("deps = %v, want %v", deps, tt.wantDeps)
+			}
+		})
+	}
+}
+
+func TestParserShowRules(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+storage: {{ templateGroup "storage" }}
+method: {{ staticList "methods" "multi" }}
+bucket: {{ input "bucket" "string" "show=methods == barmanObjectStore" }}
+class: {{ input "class" "string" "show=storage != Ephemeral" }}
+`)
+	writeFile(t, filepath.Join(dir, "methods.yaml"),
+		`{{/* inscribe: type="list" name="methods" */}}
+- barmanObjectStore
+- volumeSnapshot # show=storage == Persistent
+`)
+	writeFile(t, filepath.Join(dir, "persistent.yaml"),
+		`{{/* inscribe: type="sub-template" group="storage" description="Persistent" */}}
+persistent: true`)
+	writeFile(t, filepath.Join(dir, "ephemeral.yaml"),
+		`{{/* inscribe: type="sub-template" group="storage" description="Ephemeral" */}}
+persistent: false`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("main")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	byName := make(map[string]domain.FieldDefinition)
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	if deps := byName["bucket"].DependsOn; len(deps) != 1 || deps[0] != "methods" {
+		t.Errorf("bucket.DependsOn = %v, want [methods]", deps)
+	}
+
+	subs, err := reg.GetSubTemplates("storage")
+	if err != nil {
+		t.Fatalf("GetSubTemplates() error: %v", err)
+	}
+	var persistent, ephemeral string
+	for _, sub := range subs {
+		if sub.Description == "Persistent" {
+			persistent = sub.Content
+		} else {
+			ephemeral = sub.Content
+		}
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		values map[string]string
+		want   bool
+	}{
+		{"list value matches", "bucket", map[string]string{"methods": domain.JoinList([]string{"volumeSnapshot", "barmanObjectStore"})}, true},
+		{"list value differs", "bucket", map[string]string{"methods": "volumeSnapshot"}, false},
+		{"sub-template by content", "class", map[string]string{"storage": persistent}, true},
+		{"sub-template excluded", "class", map[string]string{"storage": ephemeral}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.EvalCondition(byName[tt.field].Condition, tt.values)
+			if err != nil {
+				t.Fatalf("EvalCondition() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("condition %q = %v, want %v", byName[tt.field].Condition, got, tt.want)
+			}
+		})
+	}
+
+	list, err := reg.GetStaticList("methods")
+	if err != nil {
+		t.Fatalf("GetStaticList() error: %v", err)
+	}
+	if deps := list.DependsOn; len(deps) != 1 || deps[0] != "storage" {
+		t.Errorf("list.DependsOn = %v, want [storage]", deps)
+	}
+	for _, tt := range []struct {
+		storage string
+		want    string
+	}{
+		{persistent, "barmanObjectStore,volumeSnapshot"},
+		{ephemeral, "barmanObjectStore"},
+	} {
+		items, err := parser.AvailableItems(list, map[string]string{"storage": tt.storage})
+		if err != nil {
+			t.Fatalf("AvailableItems() error: %v", err)
+		}
+		if got := strings.Join(items, ","); got != tt.want {
+			t.Errorf("AvailableItems() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestParserInheritance(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "base.yaml"),
+		`{{/* inscribe: type="template" name="base" command="base cmd" description="Base" */}}
+metadata:
+  name: {{ input "name" "dns-name" }}
+spec:
+  instances: {{ block "instances" . }}{{ input "instances" "integer" "default=3" }}{{ end }}
+{{- block "storage" . }}
+  storage:
+    size: {{ input "size" "memory" }}
+{{- end }}
+`)
+	writeFile(t, filepath.Join(dir, "small.yaml"),
+		`{{/* inscribe: type="template" name="small" command="small cmd" description="Small" extends="base" */}}
+{{ define "instances" }}1{{ end }}`)
+	writeFile(t, filepath.Join(dir, "small-ephemeral.yaml"),
+		`{{/* inscribe: type="template" name="small-ephemeral" command="eph cmd" description="Ephemeral" extends="small" */}}
+{{/* Keeps small's instances, drops storage */}}
+{{ define "storage" }}
+  ephemeral: {{ input "ephemeral" "memory" }}
+{{- end }}`)
+	writeFile(t, filepath.Join(dir, "broken.yaml"),
+		`{{/* inscribe: type="template" name="broken" command="broken cmd" description="Broken" extends="base" */}}
+kind: Cluster`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	tests := []struct {
+		template   string
+		wantFields string
+		values     map[string]string
+		want       string
+	}{
+		{
+			template:   "base",
+			wantFields: "name,instances,size",
+			values:     map[string]string{"name": "db", "size": "1Gi"},
+			want:       "metadata:\n  name: db\nspec:\n  instances: 3\n  storage:\n    size: 1Gi\n",
+		},
+		{
+			template:   "small",
+			wantFields: "name,size",
+			values:     map[string]string{"name": "db", "size": "1Gi"},
+			want:       "metadata:\n  name: db\nspec:\n  instances: 1\n  storage:\n    size: 1Gi\n",
+		},
+		{
+			template:   "small-ephemeral",
+			wantFields: "name,ephemeral",
+			values:     map[string]string{"name": "db", "ephemeral": "2Gi"},
+			want:       "metadata:\n  name: db\nspec:\n  instances: 1\n  ephemeral: 2Gi\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			fields, err := parser.ExtractFields(tt.template)
+			if err != nil {
+				t.Fatalf("ExtractFields() error: %v", err)
+			}
+			var names []string
+			for _, f := range fields {
+				names = append(names, f.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.wantFields {
+				t.Errorf("fields = %s, want %s", got, tt.wantFields)
+			}
+
+			got, err := parser.Render(tt.template, tt.values)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parser.ExtractFields("broken"); err == nil || !strings.Contains(err.Error(), "only define blocks") {
+		t.Errorf("expected error for content outside define blocks, got %v", err)
+	}
+}
+
+func TestParserHeaderFieldMeta(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "base.yaml"),
+		`{{/* inscribe:
+type: template
+name: base
+command: base cmd
+fields:
+  name:
+    label: Cluster name
+    help: Name of the cluster
+  instances:
+    default: "3"
+    label: Instances
+  methods:
+    default: a,b
+  comment:
+    optional: true
+*/}}
+name: {{ input "name" "dns-name" "label=Name" }}
+instances: {{ input "instances" "integer" }}
+methods: {{ staticList "methods" "multi" }}
+comment: {{ input "comment" "string" }}
+`)
+	writeFile(t, filepath.Join(dir, "child.yaml"),
+		`{{/* inscribe:
+type: template
+name: child
+command: child cmd
+extends: base
+fields:
+  instances:
+    default: "1"
+*/}}
+`)
+	writeFile(t, filepath.Join(dir, "invalid.yaml"),
+		`{{/* inscribe:
+type: template
+name: invalid
+command: invalid cmd
+fields:
+  instances:
+    default: three
+*/}}
+instances: {{ input "instances" "integer" }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("base")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if fields[0].Label != "Name" || fields[0].Description != "Name of the cluster" {
+		t.Errorf("name: inline label should win over the header's, header help should apply: %+v", fields[0])
+	}
+	if fields[1].Default != "3" || fields[1].Label != "Instances" {
+		t.Errorf("instances = %+v, want header default and label", fields[1])
+	}
+	if fields[2].Default != domain.JoinList([]string{"a", "b"}) {
+		t.Errorf("methods.Default = %q, want comma-separated header default as a list", fields[2].Default)
+	}
+	if !fields[3].Optional {
+		t.Errorf("comment should be optional")
+	}
+
+	fields, err = parser.ExtractFields("child")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if fields[1].Default != "1" || fields[1].Label != "Instances" {
+		t.Errorf("child instances = %+v, want child default and base label", fields[1])
+	}
+
+	if _, err := parser.ExtractFields("invalid"); err == nil || !strings.Contains(err.Error(), "invalid default") {
+		t.Errorf("expected invalid header default to be rejected, got %v", err)
+	}
+}
+
+func TestParserPages(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "paged.yaml"),
+		`{{/* inscribe:
+type: template
+name: paged
+command: paged cmd
+pages:
+  - name: storage
+  - name: backup
+fields:
+  bucket:
+    page: backup
+*/}}
+name: {{ input "name" "dns-name" }}
+size: {{ input "size" "string" "page=storage" }}
+bucket: {{ input "bucket" "string" }}
+`)
+	writeFile(t, filepath.Join(dir, "unknown.yaml"),
+		`{{/* inscribe: type="template" name="unknown" command="unknown cmd" */}}
+size: {{ input "size" "string" "page=storage" }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("paged")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	want := map[string]string{"name": "", "size": "storage", "bucket": "backup"}
+	for _, f := range fields {
+		if f.Page != want[f.Name] {
+			t.Errorf("%s.Page = %q, want %q", f.Name, f.Page, want[f.Name])
+		}
+	}
+
+	if _, err := parser.ExtractFields("unknown"); err == nil || !strings.Contains(err.Error(), `field "size": unknown page "storage"`) {
+		t.Errorf("expected unknown page error, got %v", err)
+	}
+}
+
+func TestParserClusterNamespace(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "copy.yaml"),
+		`{{/* inscribe: type="template" name="copy" command="copy cmd" */}}
+source: {{ autoList "source-ns" "namespace" }}
+target: {{ autoList "target-ns" "namespace" }}
+cluster: {{ autoList "source-cluster" "cnpg-clusters" "namespace=source-ns" }}
+`)
+	writeFile(t, filepath.Join(dir, "unknown.yaml"),
+		`{{/* inscribe: type="template" name="unknown" command="unknown cmd" */}}
+name: {{ input "name" "dns-name" }}
+cluster: {{ autoList "cnpg-clusters" "namespace=name" }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	fields, err := parser.ExtractFields("copy")
+	if err != nil {
+		t.Fatalf("ExtractFields() error: %v", err)
+	}
+	if got := fields[2].Namespace; got != "source-ns" {
+		t.Errorf("source-cluster.Namespace = %q, want source-ns", got)
+	}
+
+	if _, err := parser.ExtractFields("unknown"); err == nil || !strings.Contains(err.Error(), `field "cnpg-clusters": namespace "name" is not a namespace autoList field`) {
+		t.Errorf("expected namespace field error, got %v", err)
+	}
+}
+
 func TestParserTemplateNotFound(t *testing.T) {
 	dir := t.TempDir()
 	reg, err := NewRegistry(dir)
@@ -10,29 +10,40 @@ import (
 	"strings"
 
 	"inscribe/internal/domain"
+
+	"go.yaml.in/yaml/v3"
 )
 
 // Registry implements domain.TemplateRegistry by scanning a directory for templates.
 type Registry struct {
+	dir          string
 	templates    map[string]*domain.TemplateMeta
 	subTemplates map[string][]domain.SubTemplateMeta
 	staticLists  map[string]*domain.StaticListMeta
+	partials     map[string]*domain.PartialMeta
+	skipped      []error
 }
 
 var _ domain.TemplateRegistry = (*Registry)(nil)
 
-// headerRegexp matches the inscribe header comment: {{/* inscribe: key="value" ... */}}
-var headerRegexp = regexp.MustCompile(`\{\{/\*\s*inscribe:\s*(.+?)\s*\*/\}\}`)
+// headerRegexp matches an inscribe header comment at the start of its input,
+// capturing the metadata inside: {{/* inscribe: key="value" ... */}}.
+var headerRegexp = regexp.MustCompile(`^(?s)\{\{-?\s*/\*\s*inscribe:(.*?)\*/\s*-?\}\}\n?`)
+
+// commentRegexp matches any template comment at the start of its input.
+var commentRegexp = regexp.MustCompile(`^(?s)\{\{-?\s*/\*.*?\*/\s*-?\}\}`)
 
-// kvRegexp matches key="value" pairs within the header.
+// kvRegexp matches key="value" pairs within a one-line header.
 var kvRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
 
 // NewRegistry scans the given directory recursively and builds a template registry.
 func NewRegistry(dir string) (*Registry, error) {
 	r := &Registry{
+		dir:          dir,
 		templates:    make(map[string]*domain.TemplateMeta),
 		subTemplates: make(map[string][]domain.SubTemplateMeta),
 		staticLists:  make(map[string]*domain.StaticListMeta),
+		partials:     make(map[string]*domain.PartialMeta),
 	}
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -51,80 +62,324 @@ func NewRegistry(dir string) (*Registry, error) {
 	if err != nil {
 		return nil, fmt.Errorf("scanning template directory %q: %w", dir, err)
 	}
+	r.resolveInheritance()
 
 	return r, nil
 }
 
+// Skipped returns why templates found in the directory were left out of the
+// registry, in template name order.
+func (r *Registry) Skipped() []error {
+	return r.skipped
+}
+
+// resolveInheritance checks that every base template named by extends exists and
+// that no template extends itself, directly or through others. Templates failing
+// either check, and those extending them, are skipped so the others stay usable. A
+// template extending a bundle renders the bundle's documents, so it is a bundle too,
+// and it inherits the base's output filename and requirements unless it declares its
+// own.
+func (r *Registry) resolveInheritance() {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var broken []string
+	for _, name := range names {
+		if err := r.inheritChain(name); err != nil {
+			r.skipped = append(r.skipped, fmt.Errorf("skipping template %q: %w", name, err))
+			broken = append(broken, name)
+		}
+	}
+	for _, name := range broken {
+		delete(r.templates, name)
+	}
+}
+
+// inheritChain walks the extends chain of the named template, inheriting from each
+// base in turn.
+func (r *Registry) inheritChain(name string) error {
+	t := r.templates[name]
+	chain := []string{name}
+	for base := t.Extends; base != ""; base = r.templates[base].Extends {
+		if _, ok := r.templates[base]; !ok {
+			return fmt.Errorf("template %q extends unknown template %q", chain[len(chain)-1], base)
+		}
+		chain = append(chain, base)
+		if base == name {
+			return fmt.Errorf("template inheritance cycle: %s", strings.Join(chain, " -> "))
+		}
+		if len(chain) > len(r.templates) {
+			return fmt.Errorf("template %q extends a template inheritance cycle", name)
+		}
+		inherit(t, r.templates[base])
+	}
+	return nil
+}
+
 func (r *Registry) processFile(path string) error {
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("opening %q: %w", path, err)
 	}
-	defer func() { _ = f.Close() }()
 
-	scanner := bufio.NewScanner(f)
-	if !scanner.Scan() {
-		return nil // empty file
+	header, body, err := parseHeader(string(data))
+	if err != nil {
+		return fmt.Errorf("parsing header of %q: %w", path, err)
 	}
-	firstLine := scanner.Text()
-
-	header := parseHeader(firstLine)
 	if header == nil {
 		return nil // no inscribe header, skip
 	}
+	scanner := bufio.NewScanner(strings.NewReader(body))
 
-	switch header["type"] {
+	switch header.Type {
 	case "template":
-		r.templates[header["name"]] = &domain.TemplateMeta{
-			Type:        "template",
-			Name:        header["name"],
-			Command:     header["command"],
-			Description: header["description"],
-			FilePath:    path,
+		pages, err := header.pages()
+		if err != nil {
+			return fmt.Errorf("parsing header of %q: %w", path, err)
+		}
+		rules, err := header.rules()
+		if err != nil {
+			return fmt.Errorf("parsing header of %q: %w", path, err)
+		}
+		r.templates[header.Name] = &domain.TemplateMeta{
+			Type:               "template",
+			Name:               header.Name,
+			Command:            header.Command,
+			Description:        header.Description,
+			FilePath:           path,
+			Bundle:             header.Bundle,
+			Extends:            header.Extends,
+			Version:            header.Version,
+			Authors:            header.Authors,
+			Tags:               header.Tags,
+			MinInscribeVersion: header.MinInscribeVersion,
+			OutputFilename:     header.OutputFilename,
+			RequiresCRDs:       header.RequiresCRDs,
+			Fields:             header.fieldMeta(),
+			Pages:              pages,
+			Rules:              rules,
 		}
 	case "sub-template":
 		content, err := readContentAfterHeader(scanner)
 		if err != nil {
 			return fmt.Errorf("reading sub-template content from %q: %w", path, err)
 		}
-		r.subTemplates[header["group"]] = append(r.subTemplates[header["group"]], domain.SubTemplateMeta{
-			Group:       header["group"],
-			Description: header["description"],
+		r.subTemplates[header.Group] = append(r.subTemplates[header.Group], domain.SubTemplateMeta{
+			Group:       header.Group,
+			Description: header.Description,
 			Content:     content,
 			FilePath:    path,
 		})
 	case "list":
-		items, err := parseListItems(scanner)
+		list, err := parseListItems(scanner)
 		if err != nil {
 			return fmt.Errorf("reading list items from %q: %w", path, err)
 		}
-		r.staticLists[header["name"]] = &domain.StaticListMeta{
-			Name:     header["name"],
-			Items:    items,
+		list.Name = header.Name
+		list.FilePath = path
+		r.staticLists[header.Name] = list
+	case "partial":
+		content, err := readContentAfterHeader(scanner)
+		if err != nil {
+			return fmt.Errorf("reading partial content from %q: %w", path, err)
+		}
+		r.partials[header.Name] = &domain.PartialMeta{
+			Name:     header.Name,
+			Content:  content,
 			FilePath: path,
 		}
 	default:
-		return fmt.Errorf("unknown inscribe type %q in %q", header["type"], path)
+		return fmt.Errorf("unknown inscribe type %q in %q", header.Type, path)
 	}
 
 	return nil
 }
 
-// parseHeader extracts key-value pairs from an inscribe header line.
-func parseHeader(line string) map[string]string {
-	match := headerRegexp.FindStringSubmatch(line)
-	if match == nil {
+// inherit fills in the metadata t takes over from its base.
+func inherit(t, base *domain.TemplateMeta) {
+	t.Bundle = t.Bundle || base.Bundle
+	if t.OutputFilename == "" {
+		t.OutputFilename = base.OutputFilename
+	}
+	if t.MinInscribeVersion == "" {
+		t.MinInscribeVersion = base.MinInscribeVersion
+	}
+	if t.RequiresCRDs == nil {
+		t.RequiresCRDs = base.RequiresCRDs
+	}
+	if t.Pages == nil {
+		t.Pages = base.Pages
+	}
+}
+
+// fileHeader is the metadata in an inscribe header comment.
+type fileHeader struct {
+	Type        string `yaml:"type"`
+	Name        string `yaml:"name"`
+	Command     string `yaml:"command"`
+	Description string `yaml:"description"`
+	Group       string `yaml:"group"`
+	Bundle      bool   `yaml:"bundle"`
+	Extends     string `yaml:"extends"`
+
+	Version            string                 `yaml:"version"`
+	Authors            []string               `yaml:"authors"`
+	Tags               []string               `yaml:"tags"`
+	MinInscribeVersion string                 `yaml:"minInscribeVersion"`
+	OutputFilename     string                 `yaml:"outputFilename"`
+	RequiresCRDs       []string               `yaml:"requiresCRDs"`
+	Fields             map[string]fieldHeader `yaml:"fields"`
+	Pages              []pageHeader           `yaml:"pages"`
+	Rules              []ruleHeader           `yaml:"rules"`
+}
+
+// fieldHeader is the metadata declared for one field under "fields:".
+type fieldHeader struct {
+	Label       string `yaml:"label"`
+	Help        string `yaml:"help"`
+	Placeholder string `yaml:"placeholder"`
+	Default     string `yaml:"default"`
+	Optional    bool   `yaml:"optional"`
+	Page        string `yaml:"page"`
+}
+
+// pageHeader is a wizard page declared under "pages:".
+type pageHeader struct {
+	Name        string `yaml:"name"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+}
+
+// ruleHeader is a cross-field rule declared under "rules:", either as a mapping or
+// as just its expression.
+type ruleHeader struct {
+	Rule    string   `yaml:"rule"`
+	Message string   `yaml:"message"`
+	Fields  []string `yaml:"fields"`
+}
+
+func (r *ruleHeader) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Rule = node.Value
 		return nil
 	}
-	kvs := kvRegexp.FindAllStringSubmatch(match[1], -1)
-	if len(kvs) == 0 {
+	type plain ruleHeader
+	return node.Decode((*plain)(r))
+}
+
+func (h *fileHeader) fieldMeta() map[string]domain.FieldMeta {
+	if len(h.Fields) == 0 {
 		return nil
 	}
-	result := make(map[string]string)
-	for _, kv := range kvs {
-		result[kv[1]] = kv[2]
+	fields := make(map[string]domain.FieldMeta, len(h.Fields))
+	for name, f := range h.Fields {
+		fields[name] = domain.FieldMeta(f)
 	}
-	return result
+	return fields
+}
+
+// pages returns the declared wizard pages, checking that each has a unique name.
+func (h *fileHeader) pages() ([]domain.Page, error) {
+	var pages []domain.Page
+	seen := make(map[string]bool)
+	for _, p := range h.Pages {
+		if p.Name == "" {
+			return nil, fmt.Errorf("page %q has no name", p.Title)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("duplicate page %q", p.Name)
+		}
+		seen[p.Name] = true
+		pages = append(pages, domain.Page(p))
+	}
+	return pages, nil
+}
+
+// rules returns the declared cross-field rules, checking that each compiles.
+func (h *fileHeader) rules() ([]domain.Rule, error) {
+	var rules []domain.Rule
+	for _, r := range h.Rules {
+		expr, err := compileRule(r.Rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Rule, err)
+		}
+		rules = append(rules, domain.Rule{Expression: r.Rule, Message: r.Message, Fields: r.Fields, Refs: expr.refs, Compiled: expr})
+	}
+	return rules, nil
+}
+
+// parseHeader finds the inscribe header comment at the top of a file and returns its
+// metadata and the content following it, or a nil header if the file has none.
+// The header may be preceded by blank lines, "#" comment lines and other template
+// comments (e.g. a license). It holds either key="value" pairs on one line or,
+// spanning several lines, a YAML document:
+//
+//	{{/* inscribe:
+//	type: template
+//	name: cnpg-cluster
+//	tags: [postgres, cnpg]
+//	*/}}
+func parseHeader(content string) (*fileHeader, string, error) {
+	rest := content
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		if match := headerRegexp.FindStringSubmatch(rest); match != nil {
+			header, err := decodeHeader(match[1])
+			if err != nil {
+				return nil, "", err
+			}
+			return header, rest[len(match[0]):], nil
+		}
+		if strings.HasPrefix(rest, "#") {
+			_, after, _ := strings.Cut(rest, "\n")
+			rest = after
+			continue
+		}
+		if loc := commentRegexp.FindStringIndex(rest); loc != nil {
+			rest = rest[loc[1]:]
+			continue
+		}
+		return nil, content, nil
+	}
+}
+
+// decodeHeader decodes the metadata inside a header comment.
+func decodeHeader(text string) (*fileHeader, error) {
+	text = strings.TrimSpace(text)
+	if !strings.Contains(text, "\n") {
+		if kvs := kvRegexp.FindAllStringSubmatch(text, -1); len(kvs) > 0 {
+			pairs := make(map[string]string)
+			for _, kv := range kvs {
+				pairs[kv[1]] = kv[2]
+			}
+			return &fileHeader{
+				Type:               pairs["type"],
+				Name:               pairs["name"],
+				Command:            pairs["command"],
+				Description:        pairs["description"],
+				Group:              pairs["group"],
+				Bundle:             pairs["bundle"] == "true",
+				Extends:            pairs["extends"],
+				Version:            pairs["version"],
+				MinInscribeVersion: pairs["minInscribeVersion"],
+				OutputFilename:     pairs["outputFilename"],
+			}, nil
+		}
+	}
+
+	var header fileHeader
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Type == "" {
+		return nil, fmt.Errorf("missing type")
+	}
+	return &header, nil
 }
 
 // readContentAfterHeader reads all remaining content after the header line.
@@ -139,19 +394,41 @@ func readContentAfterHeader(scanner *bufio.Scanner) (string, error) {
 	return strings.Join(lines, "\n"), nil
 }
 
-// parseListItems reads YAML list items (lines starting with "- ").
-func parseListItems(scanner *bufio.Scanner) ([]string, error) {
-	var items []string
+// showRuleMarker introduces an item's visibility rule, written as a trailing comment.
+const showRuleMarker = "# show="
+
+// parseListItems reads YAML list items (lines starting with "- "). An item may end
+// with a "# show=<rule>" comment, offering it only when the rule holds.
+func parseListItems(scanner *bufio.Scanner) (*domain.StaticListMeta, error) {
+	list := &domain.StaticListMeta{}
+	conditional := false
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "- ") {
-			items = append(items, strings.TrimPrefix(line, "- "))
+		if !strings.HasPrefix(line, "- ") {
+			continue
+		}
+		item, rule, hasRule := strings.Cut(strings.TrimPrefix(line, "- "), showRuleMarker)
+		cond := ""
+		if hasRule {
+			var deps []string
+			var err error
+			cond, deps, err = compileShowRule(rule)
+			if err != nil {
+				return nil, fmt.Errorf("item %q: %w", strings.TrimSpace(item), err)
+			}
+			list.DependsOn = appendUnique(list.DependsOn, deps...)
+			conditional = true
 		}
+		list.Items = append(list.Items, strings.TrimSpace(item))
+		list.ItemConditions = append(list.ItemConditions, cond)
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("scanning list items: %w", err)
 	}
-	return items, nil
+	if !conditional {
+		list.ItemConditions = nil
+	}
+	return list, nil
 }
 
 func (r *Registry) GetTemplate(name string) (*domain.TemplateMeta, error) {
@@ -178,6 +455,14 @@ func (r *Registry) GetStaticList(name string) (*domain.StaticListMeta, error) {
 	return sl, nil
 }
 
+func (r *Registry) GetPartial(name string) (*domain.PartialMeta, error) {
+	p, ok := r.partials[name]
+	if !ok {
+		return nil, fmt.Errorf("partial %q not found", name)
+	}
+	return p, nil
+}
+
 func (r *Registry) ListTemplates() []domain.TemplateMeta {
 	var result []domain.TemplateMeta
 	for _, t := range r.templates {
@@ -201,3 +486,7 @@ func (r *Registry) ListTemplatesByCommandPrefix(prefix string) []domain.Template
 	})
 	return result
 }
+
+func (r *Registry) Dir() string {
+	return r.dir
+}
@@ -3,7 +3,11 @@ package engine
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	"inscribe/internal/domain"
 )
 
 func TestParseHeader(t *testing.T) {
@@ -31,6 +35,12 @@ func TestParseHeader(t *testing.T) {
 			wantNil:  false,
 			wantType: "list",
 		},
+		{
+			name:     "partial header",
+			line:     `{{/* inscribe: type="partial" name="std-metadata" */}}`,
+			wantNil:  false,
+			wantType: "partial",
+		},
 		{
 			name:    "no header",
 			line:    `apiVersion: v1`,
@@ -44,7 +54,10 @@ func TestParseHeader(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := parseHeader(tt.line)
+			result, _, err := parseHeader(tt.line)
+			if err != nil {
+				t.Fatalf("parseHeader() error: %v", err)
+			}
 			if tt.wantNil {
 				if result != nil {
 					t.Errorf("expected nil, got %v", result)
@@ -54,8 +67,132 @@ func TestParseHeader(t *testing.T) {
 			if result == nil {
 				t.Fatal("expected non-nil result")
 			}
-			if result["type"] != tt.wantType {
-				t.Errorf("type = %q, want %q", result["type"], tt.wantType)
+			if result.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", result.Type, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestParseHeaderFrontMatter(t *testing.T) {
+	content := `{{/*
+  Copyright 2026 The Inscribe Authors. Licensed under Apache-2.0.
+*/}}
+# Managed by the platform team
+{{/* inscribe:
+type: template
+name: cnpg-cluster
+command: cluster cnpg
+description: 'CNPG "PostgreSQL" Cluster'
+version: 1.2.0
+authors: [alice, bob]
+tags:
+  - postgres
+  - cnpg
+minInscribeVersion: 0.1.0
+outputFilename: "{{ .name }}-cluster.yaml"
+requiresCRDs: [clusters.postgresql.cnpg.io]
+fields:
+  instances:
+    label: Instances
+    default: "3"
+*/}}
+kind: Cluster
+`
+	header, body, err := parseHeader(content)
+	if err != nil {
+		t.Fatalf("parseHeader() error: %v", err)
+	}
+	if header == nil {
+		t.Fatal("expected a header")
+	}
+	if header.Type != "template" || header.Name != "cnpg-cluster" || header.Command != "cluster cnpg" {
+		t.Errorf("header = %+v", header)
+	}
+	if header.Description != `CNPG "PostgreSQL" Cluster` {
+		t.Errorf("Description = %q", header.Description)
+	}
+	if strings.Join(header.Authors, ",") != "alice,bob" || strings.Join(header.Tags, ",") != "postgres,cnpg" {
+		t.Errorf("Authors = %v, Tags = %v", header.Authors, header.Tags)
+	}
+	if header.Version != "1.2.0" || header.MinInscribeVersion != "0.1.0" || header.OutputFilename != "{{ .name }}-cluster.yaml" {
+		t.Errorf("header = %+v", header)
+	}
+	if len(header.RequiresCRDs) != 1 || header.Fields["instances"].Default != "3" {
+		t.Errorf("RequiresCRDs = %v, Fields = %v", header.RequiresCRDs, header.Fields)
+	}
+	if body != "kind: Cluster\n" {
+		t.Errorf("body = %q, want %q", body, "kind: Cluster\n")
+	}
+
+	errorTests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown key", "{{/* inscribe:\ntype: template\nnmae: x\n*/}}"},
+		{"missing type", "{{/* inscribe:\nname: x\n*/}}"},
+		{"invalid yaml", "{{/* inscribe:\ntype: [template\n*/}}"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseHeader(tt.content); err == nil {
+				t.Errorf("expected error for %q", tt.content)
+			}
+		})
+	}
+}
+
+func TestRegistryPages(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.yaml"), `{{/* inscribe:
+type: template
+name: base
+command: base cmd
+pages:
+  - name: storage
+    title: Storage
+    description: Volume size and class
+  - name: backup
+*/}}
+name: {{ input "name" "dns-name" }}
+`)
+	writeFile(t, filepath.Join(dir, "child.yaml"), `{{/* inscribe: type="template" name="child" command="child cmd" extends="base" */}}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	base, _ := reg.GetTemplate("base")
+	want := []domain.Page{
+		{Name: "storage", Title: "Storage", Description: "Volume size and class"},
+		{Name: "backup"},
+	}
+	if !slices.Equal(base.Pages, want) {
+		t.Errorf("Pages = %+v, want %+v", base.Pages, want)
+	}
+	if base.Pages[1].DisplayTitle() != "backup" {
+		t.Errorf("DisplayTitle() = %q, want the page name", base.Pages[1].DisplayTitle())
+	}
+	child, _ := reg.GetTemplate("child")
+	if !slices.Equal(child.Pages, want) {
+		t.Errorf("child Pages = %+v, want the base's", child.Pages)
+	}
+
+	errorTests := []struct {
+		name  string
+		pages string
+		want  string
+	}{
+		{"duplicate page", "pages:\n  - name: storage\n  - name: storage\n", `duplicate page "storage"`},
+		{"page without name", "pages:\n  - title: Storage\n", `page "Storage" has no name`},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "tmpl.yaml"), "{{/* inscribe:\ntype: template\nname: t\ncommand: t cmd\n"+tt.pages+"*/}}\n")
+			if _, err := NewRegistry(dir); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewRegistry() error = %v, want %q", err, tt.want)
 			}
 		})
 	}
@@ -106,6 +243,15 @@ func TestNewRegistry(t *testing.T) {
 	if list.Items[0] != "item-one" || list.Items[1] != "item-two" {
 		t.Errorf("list items = %v, want [item-one, item-two]", list.Items)
 	}
+
+	// Check partials
+	partial, err := reg.GetPartial("test-partial")
+	if err != nil {
+		t.Fatalf("GetPartial() error: %v", err)
+	}
+	if partial.Content != "labels:\n  team: {{ input \"team\" \"dns-name\" }}" {
+		t.Errorf("partial content = %q", partial.Content)
+	}
 }
 
 func TestRegistryNotFound(t *testing.T) {
@@ -129,6 +275,101 @@ func TestRegistryNotFound(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for nonexistent static list")
 	}
+
+	_, err = reg.GetPartial("nonexistent")
+	if err == nil {
+		t.Error("expected error for nonexistent partial")
+	}
+}
+
+func TestRegistryInheritance(t *testing.T) {
+	base := `{{/* inscribe: type="template" name="base" command="base cmd" description="Base" bundle="true" */}}
+kind: Cluster`
+	other := `{{/* inscribe: type="template" name="other" command="other cmd" description="Other" */}}`
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantSkipped []string
+	}{
+		{
+			name: "chain",
+			files: map[string]string{
+				"base.yaml":  base,
+				"mid.yaml":   `{{/* inscribe: type="template" name="mid" command="mid cmd" description="Mid" extends="base" */}}`,
+				"child.yaml": `{{/* inscribe: type="template" name="child" command="child cmd" description="Child" extends="mid" */}}`,
+			},
+		},
+		{
+			name: "missing base",
+			files: map[string]string{
+				"child.yaml": `{{/* inscribe: type="template" name="child" command="child cmd" description="Child" extends="nope" */}}`,
+				"other.yaml": other,
+			},
+			wantSkipped: []string{`skipping template "child": template "child" extends unknown template "nope"`},
+		},
+		{
+			name: "missing base up the chain",
+			files: map[string]string{
+				"mid.yaml":   `{{/* inscribe: type="template" name="mid" command="mid cmd" description="Mid" extends="nope" */}}`,
+				"child.yaml": `{{/* inscribe: type="template" name="child" command="child cmd" description="Child" extends="mid" */}}`,
+				"other.yaml": other,
+			},
+			wantSkipped: []string{
+				`skipping template "child": template "mid" extends unknown template "nope"`,
+				`skipping template "mid": template "mid" extends unknown template "nope"`,
+			},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"a.yaml":     `{{/* inscribe: type="template" name="a" command="a cmd" description="A" extends="b" */}}`,
+				"b.yaml":     `{{/* inscribe: type="template" name="b" command="b cmd" description="B" extends="a" */}}`,
+				"child.yaml": `{{/* inscribe: type="template" name="child" command="child cmd" description="Child" extends="a" */}}`,
+				"other.yaml": other,
+			},
+			wantSkipped: []string{
+				`skipping template "a": template inheritance cycle: a -> b -> a`,
+				`skipping template "b": template inheritance cycle: b -> a -> b`,
+				`skipping template "child": template "child" extends a template inheritance cycle`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			reg, err := NewRegistry(dir)
+			if err != nil {
+				t.Fatalf("NewRegistry() error: %v", err)
+			}
+			var skipped []string
+			for _, err := range reg.Skipped() {
+				skipped = append(skipped, err.Error())
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Fatalf("Skipped() = %q, want %q", skipped, tt.wantSkipped)
+			}
+			if tt.wantSkipped != nil {
+				if _, err := reg.GetTemplate("child"); err == nil {
+					t.Error("GetTemplate(child) found a skipped template")
+				}
+				if _, err := reg.GetTemplate("other"); err != nil {
+					t.Errorf("GetTemplate(other) error: %v", err)
+				}
+				return
+			}
+			child, err := reg.GetTemplate("child")
+			if err != nil {
+				t.Fatalf("GetTemplate() error: %v", err)
+			}
+			if child.Extends != "mid" || !child.Bundle {
+				t.Errorf("child = %+v, want extends mid and bundle inherited from base", child)
+			}
+		})
+	}
 }
 
 func setupTestTemplates(t *testing.T) string {
@@ -163,6 +404,12 @@ keyB: valueB
 - item-two
 `)
 
+	writeFile(t, filepath.Join(dir, "partial.yaml"),
+		`{{/* inscribe: type="partial" name="test-partial" */}}
+labels:
+  team: {{ input "team" "dns-name" }}
+`)
+
 	// Non-template file (should be ignored)
 	writeFile(t, filepath.Join(dir, "readme.yaml"),
 		`# This is not a template
@@ -174,6 +421,9 @@ just: data
 
 func writeFile(t *testing.T, path, content string) {
 	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("creating directory for %q: %v", path, err)
+	}
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		t.Fatalf("writing test file %q: %v", path, err)
 	}
@@ -0,0 +1,886 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"inscribe/internal/domain"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// A rule expression is a condition over the answers, in a small CEL-like language:
+//
+//	rule       := or
+//	or         := and ("||" and)*
+//	and        := not ("&&" not)*
+//	not        := "!" not | comparison
+//	comparison := sum (("<" | "<=" | ">" | ">=" | "==" | "!=") sum)?
+//	sum        := product (("+" | "-") product)*
+//	product    := unary (("*" | "/" | "%") unary)*
+//	unary      := "-" unary | postfix
+//	postfix    := operand ("." function "(" args ")")*
+//	operand    := quantity | string | "true" | "false" | function "(" args ")" |
+//	              field | "(" or ")"
+//
+// Fields are referred to by name. Their values are read as what their use needs:
+// quantities in arithmetic and ordering, so "instances * memory <= 64Gi" multiplies
+// a count by a memory size; booleans in "&&", "||" and "!"; and text when compared
+// with or added to a string, as in "startsWith(name, namespace + '-')". A function
+// may be called on its first argument, as in "name.startsWith(namespace)". Field
+// names may contain hyphens, so subtraction needs spaces around the minus sign.
+// The special field "context" holds the Kubernetes context, when one is chosen.
+
+// ruleToken matches one token of a rule expression, after leading whitespace.
+var ruleToken = regexp.MustCompile(`^(?:[0-9]+(?:\.[0-9]*)?(?:[eE][+-]?[0-9]+|[a-zA-Z]+)?|[a-zA-Z_][a-zA-Z0-9_-]*|"(?:[^"\\]|\\.)*"|'[^']*'|<=|>=|==|!=|&&|\|\||[<>+\-*/%()!.,])`)
+
+// contextField is the name rules refer to the Kubernetes context by.
+const contextField = "context"
+
+// unansweredError reports that a rule needs a field that has not been answered, so
+// it cannot be checked yet.
+type unansweredError struct{ field string }
+
+func (e *unansweredError) Error() string {
+	return fmt.Sprintf("needs an answer for %s", e.field)
+}
+
+// ruleNode is a node of a compiled rule expression.
+type ruleNode interface {
+	eval(values map[string]string) (ruleValue, error)
+	String() string
+}
+
+// valueKind is the kind of value a rule expression yields.
+type valueKind int
+
+const (
+	fieldValue  valueKind = iota // an answer, read as whatever its use needs
+	numberValue                  // an exact number
+	stringValue                  // text
+	boolValue                    // true or false
+)
+
+// ruleValue is a value of a rule expression. Numbers are exact, with the format
+// their quantities were written in, so results can be shown in the same units.
+type ruleValue struct {
+	kind   valueKind
+	text   string // Field and string values
+	field  string // Field the value is the answer to
+	num    *big.Rat
+	format resource.Format
+	b      bool
+}
+
+// newNumberValue converts a resource quantity to an exact number.
+func newNumberValue(q resource.Quantity) ruleValue {
+	num, _ := new(big.Rat).SetString(q.AsDec().String())
+	return ruleValue{kind: numberValue, num: num, format: q.Format}
+}
+
+// number reads the value as a number.
+func (v ruleValue) number() (ruleValue, error) {
+	switch v.kind {
+	case numberValue:
+		return v, nil
+	case fieldValue:
+		text := strings.TrimSpace(v.text)
+		if text == "" {
+			return ruleValue{}, &unansweredError{field: v.field}
+		}
+		q, err := resource.ParseQuantity(text)
+		if err != nil {
+			return ruleValue{}, fmt.Errorf("%s: %q is not a quantity", v.field, text)
+		}
+		return newNumberValue(q), nil
+	}
+	return ruleValue{}, fmt.Errorf("%s is not a quantity", v)
+}
+
+// boolean reads the value as true or false.
+func (v ruleValue) boolean() (bool, error) {
+	switch v.kind {
+	case boolValue:
+		return v.b, nil
+	case fieldValue:
+		switch strings.TrimSpace(v.text) {
+		case "":
+			return false, &unansweredError{field: v.field}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %q is not true or false", v.field, v.text)
+	}
+	return false, fmt.Errorf("%s is not true or false", v)
+}
+
+// str reads the value as text.
+func (v ruleValue) str() string {
+	switch v.kind {
+	case numberValue:
+		return v.String()
+	case boolValue:
+		return strconv.FormatBool(v.b)
+	}
+	return v.text
+}
+
+// String shows the value in messages: numbers as quantities, text quoted, and an
+// answer as a quantity if it is one.
+func (v ruleValue) String() string {
+	switch v.kind {
+	case numberValue:
+		n := v.num
+		if n.IsInt() && n.Num().IsInt64() {
+			return resource.NewQuantity(n.Num().Int64(), v.format).String()
+		}
+		milli := new(big.Rat).Mul(n, big.NewRat(1000, 1))
+		if milli.IsInt() && milli.Num().IsInt64() {
+			return resource.NewMilliQuantity(milli.Num().Int64(), v.format).String()
+		}
+		return n.FloatString(3)
+	case boolValue:
+		return strconv.FormatBool(v.b)
+	case fieldValue:
+		if n, err := v.number(); err == nil {
+			return n.String()
+		}
+	}
+	return strconv.Quote(v.text)
+}
+
+type literalNode struct {
+	text  string
+	value ruleValue
+}
+
+func (n literalNode) eval(map[string]string) (ruleValue, error) { return n.value, nil }
+func (n literalNode) String() string                            { return n.text }
+
+type fieldNode struct{ name string }
+
+func (n fieldNode) eval(values map[string]string) (ruleValue, error) {
+	v, ok := values[n.name]
+	if !ok {
+		return ruleValue{}, &unansweredError{field: n.name}
+	}
+	return ruleValue{kind: fieldValue, text: v, field: n.name}, nil
+}
+
+func (n fieldNode) String() string { return n.name }
+
+type groupNode struct{ inner ruleNode }
+
+func (n groupNode) eval(values map[string]string) (ruleValue, error) { return n.inner.eval(values) }
+func (n groupNode) String() string                                   { return "(" + n.inner.String() + ")" }
+
+type negNode struct{ operand ruleNode }
+
+func (n negNode) eval(values map[string]string) (ruleValue, error) {
+	v, err := evalNumber(n.operand, values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	return ruleValue{kind: numberValue, num: new(big.Rat).Neg(v.num), format: v.format}, nil
+}
+
+func (n negNode) String() string { return "-" + n.operand.String() }
+
+type notNode struct{ operand ruleNode }
+
+func (n notNode) eval(values map[string]string) (ruleValue, error) {
+	b, err := evalBool(n.operand, values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	return ruleValue{kind: boolValue, b: !b}, nil
+}
+
+func (n notNode) String() string { return "!" + n.operand.String() }
+
+// arithmeticNode adds, subtracts, multiplies, divides or takes the remainder of
+// numbers, or joins text with "+".
+type arithmeticNode struct {
+	op          string
+	left, right ruleNode
+}
+
+func (n arithmeticNode) eval(values map[string]string) (ruleValue, error) {
+	l, err := n.left.eval(values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	r, err := n.right.eval(values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	if n.op == "+" && (l.kind == stringValue || r.kind == stringValue) {
+		return ruleValue{kind: stringValue, text: l.str() + r.str()}, nil
+	}
+	if l, err = l.number(); err != nil {
+		return ruleValue{}, err
+	}
+	if r, err = r.number(); err != nil {
+		return ruleValue{}, err
+	}
+	// Binary units win, so "instances * memory" is shown in Gi rather than G.
+	format := l.format
+	if r.format == resource.BinarySI {
+		format = r.format
+	}
+	result := new(big.Rat)
+	switch n.op {
+	case "+":
+		result.Add(l.num, r.num)
+	case "-":
+		result.Sub(l.num, r.num)
+	case "*":
+		result.Mul(l.num, r.num)
+	case "/":
+		if r.num.Sign() == 0 {
+			return ruleValue{}, fmt.Errorf("%s: division by zero", n)
+		}
+		result.Quo(l.num, r.num)
+	case "%":
+		if !l.num.IsInt() || !r.num.IsInt() {
+			return ruleValue{}, fmt.Errorf("%s: remainder of a number that is not whole", n)
+		}
+		if r.num.Sign() == 0 {
+			return ruleValue{}, fmt.Errorf("%s: division by zero", n)
+		}
+		result.SetInt(new(big.Int).Rem(l.num.Num(), r.num.Num()))
+	}
+	return ruleValue{kind: numberValue, num: result, format: format}, nil
+}
+
+func (n arithmeticNode) String() string {
+	return n.left.String() + " " + n.op + " " + n.right.String()
+}
+
+// compareNode compares two values. Ordering compares numbers; equality compares
+// text if either side is text, and numbers if either side is a number or both
+// answers are quantities.
+type compareNode struct {
+	op          string
+	left, right ruleNode
+}
+
+func (n compareNode) eval(values map[string]string) (ruleValue, error) {
+	l, err := n.left.eval(values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	r, err := n.right.eval(values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	holds, err := compareValues(n.op, l, r)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	return ruleValue{kind: boolValue, b: holds}, nil
+}
+
+func (n compareNode) String() string {
+	return n.left.String() + " " + n.op + " " + n.right.String()
+}
+
+func compareValues(op string, l, r ruleValue) (bool, error) {
+	if op == "==" || op == "!=" {
+		equal, err := equalValues(l, r)
+		return equal == (op == "=="), err
+	}
+	ln, err := l.number()
+	if err != nil {
+		return false, err
+	}
+	rn, err := r.number()
+	if err != nil {
+		return false, err
+	}
+	c := ln.num.Cmp(rn.num)
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func equalValues(l, r ruleValue) (bool, error) {
+	switch {
+	case l.kind == stringValue || r.kind == stringValue:
+		return l.str() == r.str(), nil
+	case l.kind == boolValue || r.kind == boolValue:
+		lb, err := l.boolean()
+		if err != nil {
+			return false, err
+		}
+		rb, err := r.boolean()
+		return lb == rb, err
+	case l.kind == numberValue || r.kind == numberValue:
+		ln, err := l.number()
+		if err != nil {
+			return false, err
+		}
+		rn, err := r.number()
+		if err != nil {
+			return false, err
+		}
+		return ln.num.Cmp(rn.num) == 0, nil
+	}
+	ln, lerr := l.number()
+	rn, rerr := r.number()
+	if lerr == nil && rerr == nil {
+		return ln.num.Cmp(rn.num) == 0, nil
+	}
+	return l.text == r.text, nil
+}
+
+// logicNode combines conditions with "&&" or "||", evaluating the right one only
+// when it decides the result.
+type logicNode struct {
+	op          string
+	left, right ruleNode
+}
+
+func (n logicNode) eval(values map[string]string) (ruleValue, error) {
+	l, err := evalBool(n.left, values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	if l == (n.op == "||") {
+		return ruleValue{kind: boolValue, b: l}, nil
+	}
+	r, err := evalBool(n.right, values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	return ruleValue{kind: boolValue, b: r}, nil
+}
+
+func (n logicNode) String() string {
+	return n.left.String() + " " + n.op + " " + n.right.String()
+}
+
+// ruleFunc is a function rules can call, with the text of its arguments.
+type ruleFunc struct {
+	params    int
+	condition bool // Whether it yields true or false
+	call      func(args []string) (ruleValue, error)
+}
+
+// stringTest makes a function reporting whether test holds for two texts.
+func stringTest(test func(s, arg string) bool) ruleFunc {
+	return ruleFunc{params: 2, condition: true, call: func(args []string) (ruleValue, error) {
+		return ruleValue{kind: boolValue, b: test(args[0], args[1])}, nil
+	}}
+}
+
+// ruleFuncs are the functions rules can call, named as in CEL.
+var ruleFuncs = map[string]ruleFunc{
+	"startsWith": stringTest(strings.HasPrefix),
+	"endsWith":   stringTest(strings.HasSuffix),
+	"contains":   stringTest(strings.Contains),
+	"matches": {params: 2, condition: true, call: func(args []string) (ruleValue, error) {
+		re, err := regexp.Compile(args[1])
+		if err != nil {
+			return ruleValue{}, fmt.Errorf("invalid pattern %q: %w", args[1], err)
+		}
+		return ruleValue{kind: boolValue, b: re.MatchString(args[0])}, nil
+	}},
+	"size": {params: 1, call: func(args []string) (ruleValue, error) {
+		return ruleValue{kind: numberValue, num: big.NewRat(int64(utf8.RuneCountInString(args[0])), 1), format: resource.DecimalSI}, nil
+	}},
+}
+
+type callNode struct {
+	name   string
+	args   []ruleNode
+	method bool // Called on its first argument, as in "name.startsWith(prefix)"
+}
+
+func (n callNode) eval(values map[string]string) (ruleValue, error) {
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(values)
+		if err != nil {
+			return ruleValue{}, err
+		}
+		args[i] = v.str()
+	}
+	v, err := ruleFuncs[n.name].call(args)
+	if err != nil {
+		return ruleValue{}, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+func (n callNode) String() string {
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.String()
+	}
+	if n.method {
+		return args[0] + "." + n.name + "(" + strings.Join(args[1:], ", ") + ")"
+	}
+	return n.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+func evalNumber(n ruleNode, values map[string]string) (ruleValue, error) {
+	v, err := n.eval(values)
+	if err != nil {
+		return ruleValue{}, err
+	}
+	return v.number()
+}
+
+func evalBool(n ruleNode, values map[string]string) (bool, error) {
+	v, err := n.eval(values)
+	if err != nil {
+		return false, err
+	}
+	return v.boolean()
+}
+
+// isCondition reports whether a node may yield true or false: anything but
+// arithmetic, quantities, strings and functions yielding other values.
+func isCondition(n ruleNode) bool {
+	switch n := n.(type) {
+	case literalNode:
+		return n.value.kind == boolValue
+	case groupNode:
+		return isCondition(n.inner)
+	case arithmeticNode, negNode:
+		return false
+	case callNode:
+		return ruleFuncs[n.name].condition
+	}
+	return true
+}
+
+// ruleExpr is a compiled rule.
+type ruleExpr struct {
+	root ruleNode
+	refs []string // Fields referred to, in order of appearance
+}
+
+// compileRule parses a rule expression such as "limits-memory >= requests-memory".
+func compileRule(expression string) (*ruleExpr, error) {
+	p := &ruleParser{}
+	if err := p.tokenize(expression); err != nil {
+		return nil, err
+	}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if !isCondition(root) {
+		return nil, fmt.Errorf("expected a condition, such as a comparison (<, <=, >, >=, ==, !=), after %s", root)
+	}
+	return &ruleExpr{root: root, refs: p.refs}, nil
+}
+
+// Check evaluates the rule, returning an error describing the values if it does not
+// hold. It returns an *unansweredError if the rule needs a field without an answer,
+// or empty where a quantity or boolean is needed.
+func (e *ruleExpr) Check(values map[string]string) error {
+	holds, err := evalBool(e.root, values)
+	if err != nil || holds {
+		return err
+	}
+	var got []string
+	if cmp, ok := e.root.(compareNode); ok {
+		// Show the sides compared, which may be computed.
+		for _, side := range []ruleNode{cmp.left, cmp.right} {
+			if _, ok := side.(literalNode); ok {
+				continue
+			}
+			if v, err := side.eval(values); err == nil {
+				got = append(got, fmt.Sprintf("%s is %s", side, v))
+			}
+		}
+	} else {
+		for _, name := range e.refs {
+			if v, ok := values[name]; ok {
+				got = append(got, fmt.Sprintf("%s is %s", name, ruleValue{kind: fieldValue, text: v, field: name}))
+			}
+		}
+	}
+	return fmt.Errorf("must satisfy %s (%s)", e.root, strings.Join(got, ", "))
+}
+
+// ruleParser is a recursive descent parser over the tokens of a rule expression.
+type ruleParser struct {
+	tokens []string
+	pos    int
+	refs   []string
+}
+
+func (p *ruleParser) tokenize(expression string) error {
+	rest := strings.TrimSpace(expression)
+	for rest != "" {
+		m := ruleToken.FindString(rest)
+		if m == "" {
+			return fmt.Errorf("unexpected %q", rest)
+		}
+		p.tokens = append(p.tokens, m)
+		rest = strings.TrimLeft(rest[len(m):], " \t\n")
+	}
+	if len(p.tokens) == 0 {
+		return errors.New("empty rule")
+	}
+	return nil
+}
+
+func (p *ruleParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *ruleParser) next() string {
+	t := p.peek()
+	if t != "" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *ruleParser) or() (ruleNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) and() (ruleNode, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) not() (ruleNode, error) {
+	if p.peek() == "!" {
+		p.next()
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *ruleParser) comparison() (ruleNode, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains([]string{"<", "<=", ">", ">=", "==", "!="}, p.peek()) {
+		return left, nil
+	}
+	op := p.next()
+	right, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *ruleParser) sum() (ruleNode, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.next()
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmeticNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) product() (ruleNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" || p.peek() == "%" {
+		op := p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmeticNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) unary() (ruleNode, error) {
+	if p.peek() == "-" {
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{operand: operand}, nil
+	}
+	return p.postfix()
+}
+
+func (p *ruleParser) postfix() (ruleNode, error) {
+	operand, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "." {
+		p.next()
+		name := p.next()
+		if p.next() != "(" {
+			return nil, fmt.Errorf("expected a function call after %s.", operand)
+		}
+		if operand, err = p.call(name, operand); err != nil {
+			return nil, err
+		}
+	}
+	return operand, nil
+}
+
+func (p *ruleParser) operand() (ruleNode, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, errors.New("unexpected end of rule")
+	case t == "(":
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		return groupNode{inner: inner}, nil
+	case t == "true" || t == "false":
+		return literalNode{text: t, value: ruleValue{kind: boolValue, b: t == "true"}}, nil
+	case t[0] == '"':
+		s, err := strconv.Unquote(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", t)
+		}
+		return literalNode{text: t, value: ruleValue{kind: stringValue, text: s}}, nil
+	case t[0] == '\'':
+		return literalNode{text: t, value: ruleValue{kind: stringValue, text: t[1 : len(t)-1]}}, nil
+	case t[0] >= '0' && t[0] <= '9':
+		q, err := resource.ParseQuantity(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q", t)
+		}
+		return literalNode{text: t, value: newNumberValue(q)}, nil
+	case t[0] == '_' || t[0] >= 'a' && t[0] <= 'z' || t[0] >= 'A' && t[0] <= 'Z':
+		if p.peek() == "(" {
+			p.next()
+			return p.call(t, nil)
+		}
+		p.refs = appendUnique(p.refs, t)
+		return fieldNode{name: t}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t)
+}
+
+// call parses the arguments of a call to the named function, after its opening
+// parenthesis, with receiver as its first argument if not nil.
+func (p *ruleParser) call(name string, receiver ruleNode) (ruleNode, error) {
+	fn, ok := ruleFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q (use startsWith, endsWith, contains, matches or size)", name)
+	}
+	node := callNode{name: name, method: receiver != nil}
+	if receiver != nil {
+		node.args = append(node.args, receiver)
+	}
+	if p.peek() == ")" {
+		p.next()
+	} else {
+		for {
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			node.args = append(node.args, arg)
+			if t := p.next(); t == ")" {
+				break
+			} else if t != "," {
+				return nil, fmt.Errorf("missing closing parenthesis in call to %s", name)
+			}
+		}
+	}
+	if len(node.args) != fn.params {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, fn.params, len(node.args))
+	}
+	if name == "matches" {
+		if lit, ok := node.args[1].(literalNode); ok {
+			if _, err := regexp.Compile(lit.value.str()); err != nil {
+				return nil, fmt.Errorf("matches: invalid pattern %s: %w", lit, err)
+			}
+		}
+	}
+	return node, nil
+}
+
+// CheckRule reports an error if rule does not hold for values, with the rule's
+// message if it declares one. A rule needing a field that is unanswered, or empty
+// where a quantity or boolean is needed, is not checked yet; an answer that is not
+// a quantity or boolean where one is needed fails it.
+func (p *Parser) CheckRule(rule domain.Rule, values map[string]string) error {
+	var unanswered *unansweredError
+	if err := checkRule(rule, values); err != nil && !errors.As(err, &unanswered) {
+		return err
+	}
+	return nil
+}
+
+// checkRule is CheckRule, but reports a rule that cannot be checked yet with the
+// *unansweredError naming the field it needs.
+func checkRule(rule domain.Rule, values map[string]string) error {
+	expr := rule.Compiled
+	if expr == nil {
+		compiled, err := compileRule(rule.Expression)
+		if err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Expression, err)
+		}
+		expr = compiled
+	}
+	err := expr.Check(values)
+	var unanswered *unansweredError
+	if err == nil || errors.As(err, &unanswered) || rule.Message == "" {
+		return err
+	}
+	return errors.New(rule.Message)
+}
+
+// CheckRules reports each rule that does not hold for the answers in values as a
+// domain.ValidationError, on the first field it points at. Fields whose condition
+// does not hold are left out, so rules needing them are not checked. When complete,
+// the answers are final, so a rule needing a field that applies but was left empty
+// is reported as needing an answer for it instead of being skipped.
+func (p *Parser) CheckRules(fields []domain.FieldDefinition, rules []domain.Rule, values map[string]string, complete bool) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	answered := make(map[string]string, len(values))
+	if v, ok := values[contextField]; ok {
+		answered[contextField] = v
+	}
+	applies := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		holds, err := p.EvalCondition(f.Condition, values)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", f.Name, err)
+		}
+		applies[f.Name] = holds
+		// Final answers include the fields left empty.
+		if v, ok := values[f.Name]; (ok || complete) && holds {
+			answered[f.Name] = v
+		}
+	}
+	var invalid domain.ValidationErrors
+	for _, rule := range rules {
+		err := checkRule(rule, answered)
+		var unanswered *unansweredError
+		if errors.As(err, &unanswered) && (!complete || !applies[unanswered.field]) {
+			continue
+		}
+		if err != nil {
+			field := rule.Fields[0]
+			e := domain.NewValidationError(field, answered[field], err)
+			e.Rule = rule.Expression
+			invalid = append(invalid, e)
+		}
+	}
+	return invalid.Err()
+}
+
+// Rules returns the rules of a template and of the templates it extends. A base's
+// rule referring to a field the template no longer asks for, because it overrides
+// the block asking for it, is left out; the template's own rules must refer to
+// fields it asks for. A rule not saying which fields it is about is about the one
+// it refers to that is asked last, when it can first be checked.
+func (p *Parser) Rules(templateName string, fields []domain.FieldDefinition) ([]domain.Rule, error) {
+	chain, err := p.templateChain(templateName)
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[string]int, len(fields)+1)
+	order[contextField] = -1
+	for i, f := range fields {
+		order[f.Name] = i
+	}
+	var rules []domain.Rule
+	for i, meta := range chain {
+		own := i == len(chain)-1
+	next:
+		for _, rule := range meta.Rules {
+			for _, name := range append(slices.Clone(rule.Refs), rule.Fields...) {
+				if _, known := order[name]; known {
+					continue
+				}
+				if own {
+					return nil, fmt.Errorf("template %q: rule %q: unknown field %q", templateName, rule.Expression, name)
+				}
+				continue next
+			}
+			if len(rule.Fields) == 0 {
+				last := ""
+				for _, name := range rule.Refs {
+					if name != contextField && (last == "" || order[name] > order[last]) {
+						last = name
+					}
+				}
+				if last == "" {
+					return nil, fmt.Errorf("template %q: rule %q refers to no field; name the fields it is about", templateName, rule.Expression)
+				}
+				rule.Fields = []string{last}
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,361 @@
+package engine
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"inscribe/internal/domain"
+)
+
+func TestCompileRule(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantRefs   []string
+		wantErr    string
+	}{
+		{"limits-memory >= requests-memory", []string{"limits-memory", "requests-memory"}, ""},
+		{"instances * memory <= 64Gi", []string{"instances", "memory"}, ""},
+		{"(a + b) * 2 < 1e3", []string{"a", "b"}, ""},
+		{"-a != 0", []string{"a"}, ""},
+		{"a % 2 == 0", []string{"a"}, ""},
+		{"enabled", []string{"enabled"}, ""},
+		{`!enabled || method != "volumeSnapshot" && class != ''`, []string{"enabled", "method", "class"}, ""},
+		{`name.startsWith(namespace + "-")`, []string{"name", "namespace"}, ""},
+		{`matches(name, '^pg-\d+$') && size(name) <= 20`, []string{"name"}, ""},
+		{`!context.contains("prod") || instances % 2 == 1`, []string{"context", "instances"}, ""},
+		{"memory + 1", nil, "expected a condition"},
+		{`"text"`, nil, "expected a condition"},
+		{"size(name)", nil, "expected a condition"},
+		{"memory >= ", nil, "unexpected end of rule"},
+		{"a >= b c", nil, `unexpected "c"`},
+		{"(a >= b", nil, "missing closing parenthesis"},
+		{"a >= 4Gb", nil, `invalid quantity "4Gb"`},
+		{"lower(name) == 'a'", nil, `unknown function "lower"`},
+		{"startsWith(name)", nil, "startsWith takes 2 arguments, got 1"},
+		{"name.size", nil, "expected a function call after name."},
+		{"matches(name, '[')", nil, "matches: invalid pattern '['"},
+		{`name == "unterminated`, nil, `unexpected "\"unterminated"`},
+		{"", nil, "empty rule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expr, err := compileRule(tt.expression)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("compileRule(%q) error = %v, want %q", tt.expression, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compileRule(%q) error: %v", tt.expression, err)
+			}
+			if !slices.Equal(expr.refs, tt.wantRefs) {
+				t.Errorf("refs = %v, want %v", expr.refs, tt.wantRefs)
+			}
+		})
+	}
+}
+
+func TestParserCheckRule(t *testing.T) {
+	parser := NewParser(nil)
+	tests := []struct {
+		name    string
+		rule    domain.Rule
+		values  map[string]string
+		wantErr string
+	}{
+		{
+			name:   "holds",
+			rule:   domain.Rule{Expression: "limits-memory >= requests-memory"},
+			values: map[string]string{"limits-memory": "1Gi", "requests-memory": "1024Mi"},
+		},
+		{
+			name:    "mixed units",
+			rule:    domain.Rule{Expression: "limits-memory >= requests-memory"},
+			values:  map[string]string{"limits-memory": "1G", "requests-memory": "1Gi"},
+			wantErr: "must satisfy limits-memory >= requests-memory (limits-memory is 1G, requests-memory is 1Gi)",
+		},
+		{
+			name:   "product within bound",
+			rule:   domain.Rule{Expression: "instances * memory <= 64Gi"},
+			values: map[string]string{"instances": "3", "memory": "16Gi"},
+		},
+		{
+			name:    "product over bound",
+			rule:    domain.Rule{Expression: "instances * memory <= 64Gi"},
+			values:  map[string]string{"instances": "5", "memory": "16Gi"},
+			wantErr: "must satisfy instances * memory <= 64Gi (instances * memory is 80Gi)",
+		},
+		{
+			name:    "millicores",
+			rule:    domain.Rule{Expression: "limits-cpu >= requests-cpu"},
+			values:  map[string]string{"limits-cpu": "500m", "requests-cpu": "0.75"},
+			wantErr: "(limits-cpu is 500m, requests-cpu is 750m)",
+		},
+		{
+			name:    "custom message",
+			rule:    domain.Rule{Expression: "limits-cpu >= requests-cpu", Message: "the CPU limit must be at least the request"},
+			values:  map[string]string{"limits-cpu": "1", "requests-cpu": "2"},
+			wantErr: "the CPU limit must be at least the request",
+		},
+		{
+			name:   "unanswered field skips the rule",
+			rule:   domain.Rule{Expression: "limits-cpu >= requests-cpu"},
+			values: map[string]string{"limits-cpu": "1"},
+		},
+		{
+			name:    "not a quantity",
+			rule:    domain.Rule{Expression: "limits-cpu >= 1"},
+			values:  map[string]string{"limits-cpu": "lots"},
+			wantErr: `limits-cpu: "lots" is not a quantity`,
+		},
+		{
+			name:   "string comparison",
+			rule:   domain.Rule{Expression: `method != "volumeSnapshot" || class != ""`},
+			values: map[string]string{"method": "barmanObjectStore", "class": ""},
+		},
+		{
+			name:    "empty answer compared as text",
+			rule:    domain.Rule{Expression: `method != "volumeSnapshot" || class != ""`},
+			values:  map[string]string{"method": "volumeSnapshot", "class": ""},
+			wantErr: `must satisfy method != "volumeSnapshot" || class != "" (method is "volumeSnapshot", class is "")`,
+		},
+		{
+			name:   "short circuit skips unanswered field",
+			rule:   domain.Rule{Expression: `method != "volumeSnapshot" || class != ""`},
+			values: map[string]string{"method": "barmanObjectStore"},
+		},
+		{
+			name:   "odd instances",
+			rule:   domain.Rule{Expression: "instances % 2 == 1"},
+			values: map[string]string{"instances": "3"},
+		},
+		{
+			name:    "even instances",
+			rule:    domain.Rule{Expression: "instances % 2 == 1"},
+			values:  map[string]string{"instances": "4"},
+			wantErr: "must satisfy instances % 2 == 1 (instances % 2 is 0)",
+		},
+		{
+			name:    "remainder of a fraction",
+			rule:    domain.Rule{Expression: "cpu % 2 == 1"},
+			values:  map[string]string{"cpu": "500m"},
+			wantErr: "remainder of a number that is not whole",
+		},
+		{
+			name:   "method call with concatenation",
+			rule:   domain.Rule{Expression: `name.startsWith(namespace + "-")`},
+			values: map[string]string{"name": "team-a-orders", "namespace": "team-a"},
+		},
+		{
+			name:    "method call fails",
+			rule:    domain.Rule{Expression: `name.startsWith(namespace + "-")`},
+			values:  map[string]string{"name": "orders", "namespace": "team-a"},
+			wantErr: `must satisfy name.startsWith(namespace + "-") (name is "orders", namespace is "team-a")`,
+		},
+		{
+			name:   "context",
+			rule:   domain.Rule{Expression: `!context.contains("prod") || instances % 2 == 1`},
+			values: map[string]string{"context": "staging", "instances": "2"},
+		},
+		{
+			name:    "context in production",
+			rule:    domain.Rule{Expression: `!context.contains("prod") || instances % 2 == 1`, Message: "production clusters need an odd number of instances"},
+			values:  map[string]string{"context": "prod-eu", "instances": "2"},
+			wantErr: "production clusters need an odd number of instances",
+		},
+		{
+			name:   "no context skips the rule",
+			rule:   domain.Rule{Expression: `!context.contains("prod") || instances % 2 == 1`},
+			values: map[string]string{"instances": "2"},
+		},
+		{
+			name:    "boolean field",
+			rule:    domain.Rule{Expression: "!monitoring || instances > 1"},
+			values:  map[string]string{"monitoring": "true", "instances": "1"},
+			wantErr: "must satisfy !monitoring || instances > 1",
+		},
+		{
+			name:    "not a boolean",
+			rule:    domain.Rule{Expression: "monitoring && instances > 1"},
+			values:  map[string]string{"monitoring": "yes", "instances": "1"},
+			wantErr: `monitoring: "yes" is not true or false`,
+		},
+		{
+			name:   "pattern and size",
+			rule:   domain.Rule{Expression: `matches(name, '^pg-\d+$') && size(name) <= 6`},
+			values: map[string]string{"name": "pg-12"},
+		},
+		{
+			name:   "equal quantities in different units",
+			rule:   domain.Rule{Expression: "limits-memory == requests-memory"},
+			values: map[string]string{"limits-memory": "1Gi", "requests-memory": "1024Mi"},
+		},
+		{
+			name:   "empty quantity skips the rule",
+			rule:   domain.Rule{Expression: "limits-cpu >= requests-cpu"},
+			values: map[string]string{"limits-cpu": "1", "requests-cpu": ""},
+		},
+		{
+			name:    "division by zero",
+			rule:    domain.Rule{Expression: "memory / instances <= 1Gi"},
+			values:  map[string]string{"memory": "4Gi", "instances": "0"},
+			wantErr: "division by zero",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parser.CheckRule(tt.rule, tt.values)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckRule() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckRule() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParserCheckRulesComplete(t *testing.T) {
+	parser := NewParser(nil)
+
+	fields := []domain.FieldDefinition{
+		{Name: "requests-memory", Type: domain.FieldInput},
+		{Name: "limits-memory", Type: domain.FieldInput, Optional: true},
+		{Name: "burst-memory", Type: domain.FieldInput, Condition: `eq .tier "burst"`},
+	}
+	rules := []domain.Rule{
+		{Expression: "limits-memory >= requests-memory", Fields: []string{"limits-memory"}},
+		{Expression: "burst-memory >= requests-memory", Fields: []string{"burst-memory"}},
+	}
+	values := map[string]string{"requests-memory": "1Gi", "limits-memory": "", "tier": "fixed"}
+
+	if err := parser.CheckRules(fields, rules, values, false); err != nil {
+		t.Errorf("CheckRules() with answers still to come error: %v", err)
+	}
+	want := `invalid value for "limits-memory": needs an answer for limits-memory`
+	for _, values := range []map[string]string{values, {"requests-memory": "1Gi", "tier": "fixed"}} {
+		err := parser.CheckRules(fields, rules, values, true)
+		if err == nil || err.Error() != want {
+			t.Errorf("CheckRules(%v) with final answers error = %v, want %q", values, err, want)
+		}
+	}
+
+	optional := []domain.Rule{{Expression: `limits-memory == "" || limits-memory >= requests-memory`, Fields: []string{"limits-memory"}}}
+	if err := parser.CheckRules(fields, optional, map[string]string{"requests-memory": "1Gi"}, true); err != nil {
+		t.Errorf("CheckRules() allowing an empty field error: %v", err)
+	}
+}
+
+func TestParserRules(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.yaml"), `{{/* inscribe:
+type: template
+name: base
+command: base cmd
+rules:
+  - limits-memory >= requests-memory
+  - rule: instances * limits-memory <= 64Gi
+    message: at most 64Gi of memory across instances
+    fields: [instances]
+*/}}
+instances: {{ input "instances" "integer" }}
+{{- block "resources" . }}
+requests: {{ input "requests-memory" "memory" }}
+limits: {{ input "limits-memory" "memory" }}
+{{- end }}
+`)
+	writeFile(t, filepath.Join(dir, "fixed.yaml"), `{{/* inscribe:
+type: template
+name: fixed
+command: fixed cmd
+extends: base
+rules:
+  - instances <= 5
+*/}}
+{{ define "resources" }}
+limits: 1Gi
+{{- end }}
+`)
+	writeFile(t, filepath.Join(dir, "unknown.yaml"), `{{/* inscribe:
+type: template
+name: unknown
+command: unknown cmd
+rules:
+  - replicas <= 5
+*/}}
+instances: {{ input "instances" "integer" }}
+`)
+
+	writeFile(t, filepath.Join(dir, "context.yaml"), `{{/* inscribe:
+type: template
+name: context
+command: context cmd
+rules:
+  - rule: '!context.contains("prod") || instances % 2 == 1'
+  - rule: 'context != "kind"'
+*/}}
+instances: {{ input "instances" "integer" }}
+`)
+
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+	parser := NewParser(reg)
+
+	rulesOf := func(name string) []domain.Rule {
+		t.Helper()
+		fields, err := parser.ExtractFields(name)
+		if err != nil {
+			t.Fatalf("ExtractFields(%q) error: %v", name, err)
+		}
+		rules, err := parser.Rules(name, fields)
+		if err != nil {
+			t.Fatalf("Rules(%q) error: %v", name, err)
+		}
+		return rules
+	}
+
+	base := rulesOf("base")
+	if len(base) != 2 {
+		t.Fatalf("base rules = %+v, want 2", base)
+	}
+	if !slices.Equal(base[0].Fields, []string{"limits-memory"}) {
+		t.Errorf("rule without fields is reported on %v, want the field it refers to that is asked last", base[0].Fields)
+	}
+	if !slices.Equal(base[1].Fields, []string{"instances"}) || base[1].Message != "at most 64Gi of memory across instances" {
+		t.Errorf("rule = %+v", base[1])
+	}
+	if base[0].Compiled == nil || base[1].Compiled == nil {
+		t.Errorf("rules should be compiled when the template is loaded, got %+v", base)
+	}
+
+	fixed := rulesOf("fixed")
+	if len(fixed) != 1 || fixed[0].Expression != "instances <= 5" {
+		t.Errorf("fixed rules = %+v, want only its own, the base's refer to fields it overrides", fixed)
+	}
+
+	if _, err := parser.ExtractFields("unknown"); err == nil || !strings.Contains(err.Error(), `rule "replicas <= 5": unknown field "replicas"`) {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+
+	fields, err := parser.ExtractFields("context")
+	if err == nil {
+		_, err = parser.Rules("context", fields)
+	}
+	if err == nil || !strings.Contains(err.Error(), `rule "context != \"kind\"" refers to no field`) {
+		t.Errorf("expected error for a rule about no field, got %v", err)
+	}
+
+	bad := t.TempDir()
+	writeFile(t, filepath.Join(bad, "bad.yaml"), "{{/* inscribe:\ntype: template\nname: bad\ncommand: bad cmd\nrules:\n  - memory >=\n*/}}\n")
+	if _, err := NewRegistry(bad); err == nil || !strings.Contains(err.Error(), `rule "memory >=": unexpected end of rule`) {
+		t.Errorf("expected rule syntax error, got %v", err)
+	}
+}
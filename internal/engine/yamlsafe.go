@@ -0,0 +1,404 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"inscribe/internal/domain"
+
+	yamlv2 "go.yaml.in/yaml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// Actions that print a field value are rewritten after parsing to pipe the value
+// through yamlValue, which writes it as a YAML scalar of its validation type.
+// Other actions involving fields pipe through yamlTrack, which only records where
+// the fields ended up so invalid output can be traced back to them.
+const (
+	yamlValueFunc = "yamlValue"
+	yamlTrackFunc = "yamlTrack"
+)
+
+// rawOption is the field option that writes a value as-is, for a value that is
+// only part of a scalar or is already YAML.
+const rawOption = "raw"
+
+// Scalar modes decide how a field value is written.
+const (
+	scalarString = "string" // quoted when it would not read back as the same string
+	scalarNumber = "number" // validated as numeric; written as-is
+	scalarBool   = "bool"   // validated as a boolean; written as-is
+	scalarRaw    = "raw"    // written as-is
+)
+
+// typedValidationTypes are validation types whose values are meant to be YAML numbers
+// or booleans, by type name so "integer(min=1)" is numeric too. Values of any other
+// type, quantities included, are strings.
+var typedValidationTypes = map[string]string{
+	"integer": scalarNumber,
+	"port":    scalarNumber,
+	"boolean": scalarBool,
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// prepareYAML rewrites every template associated with tmpl so field values are
+// written YAML-safely. It must be called after parsing and before executeYAML.
+func prepareYAML(tmpl *template.Template) {
+	types := make(map[string]string)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			declaredTypes(t.Tree.Root, types)
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			annotateList(t.Tree, t.Tree.Root, types, nil)
+		}
+	}
+}
+
+// declaredTypes records the validation type of every input declared in node, so
+// .name references are written like the input itself.
+func declaredTypes(node parse.Node, types map[string]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			declaredTypes(child, types)
+		}
+	case *parse.ActionNode:
+		declaredTypes(n.Pipe, types)
+	case *parse.IfNode:
+		declaredTypes(n.Pipe, types)
+		declaredTypes(n.List, types)
+		declaredTypes(n.ElseList, types)
+	case *parse.RangeNode:
+		declaredTypes(n.Pipe, types)
+		declaredTypes(n.List, types)
+		declaredTypes(n.ElseList, types)
+	case *parse.WithNode:
+		declaredTypes(n.Pipe, types)
+		declaredTypes(n.List, types)
+		declaredTypes(n.ElseList, types)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			if name, vt, ok := inputCall(cmd); ok {
+				types[name] = vt
+			}
+			for _, arg := range cmd.Args {
+				declaredTypes(arg, types)
+			}
+		}
+	}
+}
+
+// inputCall reports whether cmd calls input, returning the field name and its
+// validation type.
+func inputCall(cmd *parse.CommandNode) (name, validationType string, ok bool) {
+	if len(cmd.Args) < 3 {
+		return "", "", false
+	}
+	ident, isIdent := cmd.Args[0].(*parse.IdentifierNode)
+	first, isName := cmd.Args[1].(*parse.StringNode)
+	vt, isType := cmd.Args[2].(*parse.StringNode)
+	if !isIdent || ident.Ident != "input" || !isName || !isType {
+		return "", "", false
+	}
+	return first.Text, vt.Text, true
+}
+
+// listScope describes the entries of a repeatable field being ranged over, so that
+// {{ . }} and {{ .key }} inside the range are written like the field itself.
+type listScope struct {
+	field string
+	types map[string]string // entry key → validation type; "" for plain entries
+}
+
+// annotateList rewrites the actions of list. types holds the validation types of
+// the inputs declared in the template.
+func annotateList(tree *parse.Tree, list *parse.ListNode, types map[string]string, scope *listScope) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			annotateAction(tree, n, types, scope)
+		case *parse.IfNode:
+			annotateList(tree, n.List, types, scope)
+			annotateList(tree, n.ElseList, types, scope)
+		case *parse.RangeNode:
+			annotateList(tree, n.List, types, rangeScope(n.Pipe))
+			annotateList(tree, n.ElseList, types, scope)
+		case *parse.WithNode:
+			annotateList(tree, n.List, types, nil)
+			annotateList(tree, n.ElseList, types, scope)
+		}
+	}
+}
+
+// rangeScope returns the scope of a range over an inputList call, or nil.
+func rangeScope(pipe *parse.PipeNode) *listScope {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) < 3 {
+		return nil
+	}
+	args := pipe.Cmds[0].Args
+	ident, ok := args[0].(*parse.IdentifierNode)
+	if !ok || ident.Ident != "inputList" {
+		return nil
+	}
+	name, ok := args[1].(*parse.StringNode)
+	vt, ok2 := args[2].(*parse.StringNode)
+	if !ok || !ok2 {
+		return nil
+	}
+	scope := &listScope{field: name.Text, types: map[string]string{"": vt.Text}}
+	if schema, isObject := domain.ParseObjectSchema(vt.Text); isObject {
+		for _, f := range schema {
+			scope.types[f.Name] = f.ValidationType
+		}
+	}
+	return scope
+}
+
+func annotateAction(tree *parse.Tree, action *parse.ActionNode, types map[string]string, scope *listScope) {
+	pipe := action.Pipe
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 {
+		return
+	}
+	fields := pipeFieldNames(pipe, scope)
+	if len(fields) == 0 {
+		return
+	}
+	if name, mode, ok := scalarOutput(pipe.Cmds[len(pipe.Cmds)-1], types, scope); ok {
+		pipe.Cmds = append(pipe.Cmds, newFuncCommand(tree, action.Pos, yamlValueFunc, name, mode))
+		return
+	}
+	pipe.Cmds = append(pipe.Cmds, newFuncCommand(tree, action.Pos, yamlTrackFunc, strings.Join(fields, ",")))
+}
+
+// scalarOutput reports whether cmd yields a single field value unchanged, returning
+// the field name and its scalar mode.
+func scalarOutput(cmd *parse.CommandNode, types map[string]string, scope *listScope) (name, mode string, ok bool) {
+	if len(cmd.Args) == 1 {
+		switch arg := cmd.Args[0].(type) {
+		case *parse.DotNode:
+			if scope != nil {
+				return scope.field, validationScalarMode(scope.types[""]), true
+			}
+		case *parse.FieldNode:
+			if len(arg.Ident) != 1 {
+				break
+			}
+			if scope != nil {
+				return scope.field, validationScalarMode(scope.types[arg.Ident[0]]), true
+			}
+			return arg.Ident[0], validationScalarMode(types[arg.Ident[0]]), true
+		}
+		return "", "", false
+	}
+	ident, isIdent := cmd.Args[0].(*parse.IdentifierNode)
+	if !isIdent {
+		return "", "", false
+	}
+	first, isString := cmd.Args[1].(*parse.StringNode)
+	if !isString {
+		return "", "", false
+	}
+	// The computed value of derive comes before its options.
+	opts := cmd.Args[2:]
+	if ident.Ident == "derive" && len(opts) > 0 {
+		opts = opts[1:]
+	}
+	for _, arg := range opts {
+		if s, ok := arg.(*parse.StringNode); ok && s.Text == rawOption {
+			return first.Text, scalarRaw, true
+		}
+	}
+	switch ident.Ident {
+	case "input":
+		if _, vt, ok := inputCall(cmd); ok {
+			return first.Text, validationScalarMode(vt), true
+		}
+		return first.Text, scalarString, true
+	case "autoList", "derive":
+		return first.Text, scalarString, true
+	case "staticList":
+		for _, arg := range cmd.Args[2:] {
+			if s, ok := arg.(*parse.StringNode); ok && s.Text == "multi" {
+				return "", "", false
+			}
+		}
+		return first.Text, scalarString, true
+	}
+	return "", "", false
+}
+
+func validationScalarMode(validationType string) string {
+	if mode, ok := typedValidationTypes[domain.ValidationTypeName(validationType)]; ok {
+		return mode
+	}
+	return scalarString
+}
+
+// pipeFieldNames returns the fields a pipeline reads, through field functions,
+// .name references, or the entries of a ranged-over repeatable field.
+func pipeFieldNames(pipe *parse.PipeNode, scope *listScope) []string {
+	var names []string
+	for _, cmd := range pipe.Cmds {
+		if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && (fieldFuncs[ident.Ident] || ident.Ident == "derive") && len(cmd.Args) > 1 {
+			if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+				names = appendUnique(names, s.Text)
+			}
+		}
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.DotNode:
+				if scope != nil {
+					names = appendUnique(names, scope.field)
+				}
+			case *parse.FieldNode:
+				if scope != nil {
+					names = appendUnique(names, scope.field)
+				} else {
+					names = appendUnique(names, a.Ident[0])
+				}
+			case *parse.PipeNode:
+				names = appendUnique(names, pipeFieldNames(a, scope)...)
+			}
+		}
+	}
+	return names
+}
+
+func newFuncCommand(tree *parse.Tree, pos parse.Pos, name string, args ...string) *parse.CommandNode {
+	cmd := &parse.CommandNode{NodeType: parse.NodeCommand, Pos: pos}
+	cmd.Args = append(cmd.Args, parse.NewIdentifier(name).SetTree(tree).SetPos(pos))
+	for _, arg := range args {
+		cmd.Args = append(cmd.Args, &parse.StringNode{NodeType: parse.NodeString, Pos: pos, Quoted: strconv.Quote(arg), Text: arg})
+	}
+	return cmd
+}
+
+// yamlOutput is the destination of a YAML-safe execution, which records the
+// output lines each field was written on.
+type yamlOutput struct {
+	buf     bytes.Buffer
+	fields  map[int][]string // output line → fields written on it
+	lines   int              // newlines in buf up to counted
+	counted int
+}
+
+// executeYAML executes a template prepared with prepareYAML.
+func executeYAML(tmpl *template.Template, data interface{}) (*yamlOutput, error) {
+	out := &yamlOutput{fields: make(map[int][]string)}
+	tmpl.Funcs(template.FuncMap{
+		yamlValueFunc: out.value,
+		yamlTrackFunc: out.track,
+	})
+	if err := tmpl.Execute(&out.buf, data); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// value writes a field value as a scalar of its mode: numbers, booleans and raw
+// values as they are, and strings quoted when they are not safe as plain scalars.
+func (o *yamlOutput) value(name, mode string, v interface{}) string {
+	s := toString(v)
+	o.record(name, s)
+	if mode == scalarString {
+		return yamlString(s)
+	}
+	return s
+}
+
+// track records which lines the fields of a formatted value were written on.
+func (o *yamlOutput) track(names string, v interface{}) interface{} {
+	s := fmt.Sprint(v)
+	for _, name := range strings.Split(names, ",") {
+		o.record(name, s)
+	}
+	return v
+}
+
+func (o *yamlOutput) record(name, value string) {
+	written := o.buf.Bytes()
+	o.lines += bytes.Count(written[o.counted:], []byte("\n"))
+	o.counted = len(written)
+	start := o.lines + 1
+	end := start + strings.Count(value, "\n")
+	for line := start; line <= end; line++ {
+		o.fields[line] = appendUnique(o.fields[line], name)
+	}
+}
+
+// yamlString returns s as a YAML scalar that reads back as the string s, whether
+// in block or flow context: plain when that is safe, double-quoted otherwise.
+func yamlString(s string) string {
+	if plainStringSafe(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// plainStringSafe reports whether s can be written as a plain scalar and still be
+// read as the string s, also inside a flow collection.
+func plainStringSafe(s string) bool {
+	if s == "" || strings.ContainsAny(s, "\n\r,[]{}") {
+		return false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(s), &doc); err != nil || len(doc.Content) != 1 {
+		return false
+	}
+	node := doc.Content[0]
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" || node.Style != 0 || node.Value != s {
+		return false
+	}
+	// Kubernetes reads manifests as YAML 1.1, where e.g. yes and on are booleans.
+	var v interface{}
+	if err := yamlv2.Unmarshal([]byte(s), &v); err != nil {
+		return false
+	}
+	_, isString := v.(string)
+	return isString
+}
+
+// checkYAML parses every document in rendered and, if it is not valid YAML,
+// names the fields written on or just before the line the error was reported for.
+func checkYAML(rendered string, fields map[int][]string) error {
+	dec := yaml.NewDecoder(strings.NewReader(rendered))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			for _, l := range []int{line, line - 1} {
+				if names := fields[l]; len(names) > 0 {
+					return fmt.Errorf("rendered output is not valid YAML near field %q: %w", strings.Join(names, `", "`), err)
+				}
+			}
+		}
+		return fmt.Errorf("rendered output is not valid YAML: %w", err)
+	}
+}
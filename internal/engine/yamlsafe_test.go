@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"inscribe/internal/domain"
+)
+
+func TestParserRenderYAMLSafe(t *testing.T) {
+	tests := []struct {
+		name   string
+		tmpl   string
+		values map[string]string
+		want   string
+	}{
+		{
+			name:   "plain safe value",
+			tmpl:   `name: {{ input "v" "string" }}`,
+			values: map[string]string{"v": "orders"},
+			want:   "name: orders",
+		},
+		{
+			name:   "colon is quoted",
+			tmpl:   `note: {{ input "v" "string" }}`,
+			values: map[string]string{"v": "a: b"},
+			want:   `note: "a: b"`,
+		},
+		{
+			name:   "comment marker is quoted",
+			tmpl:   `note: {{ input "v" "string" }}`,
+			values: map[string]string{"v": "x # y"},
+			want:   `note: "x # y"`,
+		},
+		{
+			name:   "newline is escaped",
+			tmpl:   `note: {{ input "v" "string" }}`,
+			values: map[string]string{"v": "a\nkind: Secret"},
+			want:   `note: "a\nkind: Secret"`,
+		},
+		{
+			name:   "numeric string is quoted",
+			tmpl:   `note: {{ input "v" "string" }}`,
+			values: map[string]string{"v": "123"},
+			want:   `note: "123"`,
+		},
+		{
+			name:   "YAML 1.1 boolean is quoted",
+			tmpl:   `note: {{ input "v" "string" }}`,
+			values: map[string]string{"v": "yes"},
+			want:   `note: "yes"`,
+		},
+		{
+			name:   "integer stays a number",
+			tmpl:   `instances: {{ input "v" "integer" }}`,
+			values: map[string]string{"v": "3"},
+			want:   "instances: 3",
+		},
+		{
+			name:   "bounded integer stays a number",
+			tmpl:   `instances: {{ input "v" "integer(min=1,max=9)" }}`,
+			values: map[string]string{"v": "3"},
+			want:   "instances: 3",
+		},
+		{
+			name:   "boolean stays a boolean",
+			tmpl:   `enabled: {{ input "v" "boolean" }}`,
+			values: map[string]string{"v": "true"},
+			want:   "enabled: true",
+		},
+		{
+			name:   "boolean-looking label value is quoted",
+			tmpl:   `tier: {{ input "v" "label-value" }}`,
+			values: map[string]string{"v": "true"},
+			want:   `tier: "true"`,
+		},
+		{
+			name:   "quantity is a string",
+			tmpl:   "cpu: {{ input \"a\" \"cpu\" }}\nlimit: {{ input \"b\" \"cpu\" }}",
+			values: map[string]string{"a": "500m", "b": "2"},
+			want:   "cpu: 500m\nlimit: \"2\"",
+		},
+		{
+			name:   "inside flow sequence",
+			tmpl:   `args: [{{ input "v" "string" }}]`,
+			values: map[string]string{"v": "a,b"},
+			want:   `args: ["a,b"]`,
+		},
+		{
+			name:   "whole scalar before a comment is quoted",
+			tmpl:   `note: {{ input "v" "string" }} # comment`,
+			values: map[string]string{"v": "123"},
+			want:   `note: "123" # comment`,
+		},
+		{
+			name:   "whole scalar as a mapping key is quoted",
+			tmpl:   `{{ input "v" "string" }}: value`,
+			values: map[string]string{"v": "a: b"},
+			want:   `"a: b": value`,
+		},
+		{
+			name:   "field reference takes the type of its input",
+			tmpl:   "a: {{ input \"v\" \"string\" }}\nb: {{ .v }}\nc: {{ input \"n\" \"integer\" }}\nd: {{ .n }}",
+			values: map[string]string{"v": "a: b", "n": "3"},
+			want:   "a: \"a: b\"\nb: \"a: b\"\nc: 3\nd: 3",
+		},
+		{
+			name:   "derived value is a string",
+			tmpl:   `port: {{ derive "p" "5432" }}`,
+			values: map[string]string{},
+			want:   `port: "5432"`,
+		},
+		{
+			name:   "raw option",
+			tmpl:   `image: postgres:{{ input "v" "string" "raw" }}`,
+			values: map[string]string{"v": "16.2"},
+			want:   "image: postgres:16.2",
+		},
+		{
+			name:   "raw option on derive",
+			tmpl:   `port: {{ derive "p" "5432" "raw" }}`,
+			values: map[string]string{},
+			want:   "port: 5432",
+		},
+		{
+			name:   "block scalar through nindent",
+			tmpl:   "script: |{{ input \"v\" \"string\" | nindent 2 }}",
+			values: map[string]string{"v": "echo a\necho b"},
+			want:   "script: |\n  echo a\n  echo b",
+		},
+		{
+			name:   "explicit formatting is left alone",
+			tmpl:   `note: {{ input "v" "string" | squote }}`,
+			values: map[string]string{"v": "a: b"},
+			want:   `note: 'a: b'`,
+		},
+		{
+			name:   "raw escape hatch",
+			tmpl:   `note: {{ input "v" "string" | raw }}`,
+			values: map[string]string{"v": "[a, b]"},
+			want:   `note: [a, b]`,
+		},
+		{
+			name:   "repeatable entries",
+			tmpl:   "ports:\n{{- range inputList \"ports\" \"name:string,port:port\" }}\n  - name: {{ .name }}\n    port: {{ .port }}\n{{- end }}",
+			values: map[string]string{"ports": domain.JoinList([]string{"name=on,port=80"})},
+			want:   "ports:\n  - name: \"on\"\n    port: 80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "main.yaml"),
+				`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}`+"\n"+tt.tmpl)
+			reg, err := NewRegistry(dir)
+			if err != nil {
+				t.Fatalf("NewRegistry() error: %v", err)
+			}
+			got, err := NewParser(reg).Render("main", tt.values)
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParserRenderInvalidYAMLNamesField(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.yaml"),
+		`{{/* inscribe: type="template" name="main" command="main" description="Main" */}}
+name: {{ input "name" "dns-name" }}
+labels: {{ input "labels" "string" | raw }}
+`)
+	reg, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error: %v", err)
+	}
+
+	_, err = NewParser(reg).Render("main", map[string]string{"name": "db", "labels": "a: b: c"})
+	if err == nil {
+		t.Fatal("expected error for invalid YAML")
+	}
+	if !strings.Contains(err.Error(), `"labels"`) {
+		t.Errorf("error should name the offending field, got: %v", err)
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"orders", "orders"},
+		{"say \"hi\"", "say \"hi\""},
+		{"", `""`},
+		{"123", `"123"`},
+		{"1e3", `"1e3"`},
+		{"on", `"on"`},
+		{"null", `"null"`},
+		{"~", `"~"`},
+		{"a: b", `"a: b"`},
+		{"x # y", `"x # y"`},
+		{" padded", `" padded"`},
+		{"- item", `"- item"`},
+		{"*alias", `"*alias"`},
+		{"!tag", `"!tag"`},
+		{"a,b", `"a,b"`},
+		{"line\nbreak", `"line\nbreak"`},
+	}
+	for _, tt := range tests {
+		if got := yamlString(tt.in); got != tt.want {
+			t.Errorf("yamlString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -4,13 +4,20 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 
 	"inscribe/internal/domain"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -21,8 +28,19 @@ var cnpgClusterGVR = schema.GroupVersionResource{
 }
 
 // Client implements domain.KubeClient using real Kubernetes connections.
+// Connections to each context are set up once and reused by later calls.
 type Client struct {
 	kubeconfig string
+
+	mu    sync.Mutex
+	conns map[string]*connection // by context
+}
+
+// connection is what lookups in one context share: the API resources the
+// cluster serves, discovered on first use, and a dynamic client.
+type connection struct {
+	mapper  meta.RESTMapper
+	dynamic dynamic.Interface
 }
 
 var _ domain.KubeClient = (*Client)(nil)
@@ -97,11 +115,94 @@ func (c *Client) ListCNPGClusters(ctx string, namespace string) ([]string, error
 	return clusters, nil
 }
 
-func (c *Client) clientsetForContext(ctx string) (*k8s.Clientset, error) {
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+// Lookup returns the object of the given apiVersion and kind named name, or the list
+// of all such objects when name is empty, as unstructured content. The namespace is
+// ignored for cluster-scoped kinds; empty, it lists across all namespaces. A missing
+// object, or a kubeconfig without any cluster, yields nil.
+func (c *Client) Lookup(ctx, apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	conn, err := c.connection(ctx)
+	if err != nil {
+		if clientcmd.IsEmptyConfig(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("parsing apiVersion %q: %w", apiVersion, err)
+	}
+	mapping, err := conn.mapper.RESTMapping(gv.WithKind(kind).GroupKind(), gv.Version)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s %s: %w", apiVersion, kind, err)
+	}
+	dynClient := conn.dynamic
+
+	var resource dynamic.ResourceInterface = dynClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && namespace != "" {
+		resource = dynClient.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	if name == "" {
+		list, err := resource.List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", mapping.Resource.Resource, err)
+		}
+		return list.UnstructuredContent(), nil
+	}
+	obj, err := resource.Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting %s %q: %w", mapping.Resource.Resource, name, err)
+	}
+	return obj.UnstructuredContent(), nil
+}
+
+// connection returns the connection to the cluster of ctx, setting it up on first use.
+func (c *Client) connection(ctx string) (*connection, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn, ok := c.conns[ctx]; ok {
+		return conn, nil
+	}
+	config, err := c.restConfigForContext(ctx)
+	if clientcmd.IsEmptyConfig(err) {
+		// Returned as is, so callers can tell a kubeconfig without clusters apart.
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("building client config for context %q: %w", ctx, err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("creating discovery client for context %q: %w", ctx, err)
+	}
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("creating dynamic client for context %q: %w", ctx, err)
+	}
+	conn := &connection{
+		mapper:  restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		dynamic: dynClient,
+	}
+	if c.conns == nil {
+		c.conns = make(map[string]*connection)
+	}
+	c.conns[ctx] = conn
+	return conn, nil
+}
+
+func (c *Client) restConfigForContext(ctx string) (*rest.Config, error) {
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		c.loadingRules(),
 		&clientcmd.ConfigOverrides{CurrentContext: ctx},
 	).ClientConfig()
+}
+
+func (c *Client) clientsetForContext(ctx string) (*k8s.Clientset, error) {
+	config, err := c.restConfigForContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("building client config for context %q: %w", ctx, err)
 	}
@@ -113,16 +214,9 @@ func (c *Client) clientsetForContext(ctx string) (*k8s.Clientset, error) {
 }
 
 func (c *Client) dynamicClientForContext(ctx string) (dynamic.Interface, error) {
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		c.loadingRules(),
-		&clientcmd.ConfigOverrides{CurrentContext: ctx},
-	).ClientConfig()
+	conn, err := c.connection(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("building client config for context %q: %w", ctx, err)
-	}
-	dynClient, err := dynamic.NewForConfig(config)
-	if err != nil {
-		return nil, fmt.Errorf("creating dynamic client for context %q: %w", ctx, err)
+		return nil, err
 	}
-	return dynClient, nil
+	return conn.dynamic, nil
 }
@@ -1,6 +1,12 @@
 package kubernetes
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 )
 
@@ -62,3 +68,110 @@ func TestMockClientListCNPGClusters(t *testing.T) {
 		t.Errorf("expected 0 clusters for minikube, got %d", len(clusters))
 	}
 }
+
+func TestMockClientLookup(t *testing.T) {
+	mock := NewMockClient()
+
+	obj, err := mock.Lookup("production", "postgresql.cnpg.io/v1", "Cluster", "app-prod", "main-db")
+	if err != nil {
+		t.Fatalf("Lookup() error: %v", err)
+	}
+	spec, _ := obj["spec"].(map[string]interface{})
+	storage, _ := spec["storage"].(map[string]interface{})
+	if storage["storageClass"] != "fast-ssd" {
+		t.Errorf("storageClass = %v, want fast-ssd", storage["storageClass"])
+	}
+
+	// Missing object
+	obj, err = mock.Lookup("production", "postgresql.cnpg.io/v1", "Cluster", "app-prod", "missing")
+	if err != nil || obj != nil {
+		t.Errorf("Lookup() missing = %v, %v; want nil, nil", obj, err)
+	}
+
+	// Wrong namespace
+	obj, _ = mock.Lookup("production", "postgresql.cnpg.io/v1", "Cluster", "default", "main-db")
+	if obj != nil {
+		t.Errorf("expected no object in another namespace, got %v", obj)
+	}
+
+	// List across namespaces
+	list, err := mock.Lookup("production", "v1", "Secret", "", "")
+	if err != nil {
+		t.Fatalf("Lookup() list error: %v", err)
+	}
+	items, _ := list["items"].([]interface{})
+	if list["kind"] != "SecretList" || len(items) != 1 {
+		t.Errorf("list = %v, want a SecretList with 1 item", list)
+	}
+
+	// Empty list in another context
+	list, _ = mock.Lookup("staging", "v1", "Secret", "", "")
+	if items, _ := list["items"].([]interface{}); len(items) != 0 {
+		t.Errorf("expected an empty list, got %v", list)
+	}
+}
+
+// fakeAPIServer serves discovery and a single ConfigMap, counting discovery requests.
+func fakeAPIServer(t *testing.T, discoveries *atomic.Int32) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		discoveries.Add(1)
+		reply(w, `{"kind":"APIVersions","versions":["v1"]}`)
+	})
+	mux.HandleFunc("/apis", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, `{"kind":"APIGroupList","groups":[]}`)
+	})
+	mux.HandleFunc("/api/v1", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, `{"kind":"APIResourceList","groupVersion":"v1","resources":[{"name":"configmaps","singularName":"configmap","namespaced":true,"kind":"ConfigMap","verbs":["get","list"]}]}`)
+	})
+	mux.HandleFunc("/api/v1/namespaces/app/configmaps/settings", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"app"}}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+    user: fake
+users:
+- name: fake
+  user: {}
+current-context: fake
+`, server.URL)
+	if err := os.WriteFile(kubeconfig, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return kubeconfig
+}
+
+func TestClientLookupDiscoversOnce(t *testing.T) {
+	var discoveries atomic.Int32
+	client := NewClient(fakeAPIServer(t, &discoveries))
+
+	for range 3 {
+		obj, err := client.Lookup("fake", "v1", "ConfigMap", "app", "settings")
+		if err != nil {
+			t.Fatalf("Lookup() error: %v", err)
+		}
+		if obj["kind"] != "ConfigMap" {
+			t.Fatalf("Lookup() = %v, want the ConfigMap", obj)
+		}
+	}
+	if n := discoveries.Load(); n != 1 {
+		t.Errorf("discovered the API %d times for 3 lookups, want once", n)
+	}
+}
@@ -44,3 +44,20 @@ func TestRealClientListCNPGClusters(t *testing.T) {
 	}
 	t.Logf("CNPG Clusters (all): %v", allClusters)
 }
+
+func TestRealClientLookup(t *testing.T) {
+	client := NewClient("")
+	obj, err := client.Lookup("minikube", "v1", "Namespace", "", "default")
+	if err != nil {
+		t.Fatalf("Lookup() error: %v", err)
+	}
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata["name"] != "default" {
+		t.Errorf("expected the default namespace, got %v", obj)
+	}
+
+	obj, err = client.Lookup("minikube", "v1", "Namespace", "", "does-not-exist")
+	if err != nil || obj != nil {
+		t.Errorf("Lookup() missing = %v, %v; want nil, nil", obj, err)
+	}
+}
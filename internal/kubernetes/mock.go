@@ -9,8 +9,9 @@ import (
 // MockClient implements domain.KubeClient for testing.
 type MockClient struct {
 	Contexts     []string
-	Namespaces   map[string][]string            // context → namespaces
-	CNPGClusters map[string]map[string][]string  // context → namespace → clusters
+	Namespaces   map[string][]string                 // context → namespaces
+	CNPGClusters map[string]map[string][]string      // context → namespace → clusters
+	Objects      map[string][]map[string]interface{} // context → objects, as unstructured content
 }
 
 var _ domain.KubeClient = (*MockClient)(nil)
@@ -32,6 +33,25 @@ func NewMockClient() *MockClient {
 				"app-staging": {"staging-db"},
 			},
 		},
+		Objects: map[string][]map[string]interface{}{
+			"production": {
+				{
+					"apiVersion": "postgresql.cnpg.io/v1",
+					"kind":       "Cluster",
+					"metadata":   map[string]interface{}{"name": "main-db", "namespace": "app-prod"},
+					"spec": map[string]interface{}{
+						"instances": int64(3),
+						"storage":   map[string]interface{}{"size": "100Gi", "storageClass": "fast-ssd"},
+					},
+				},
+				{
+					"apiVersion": "v1",
+					"kind":       "Secret",
+					"metadata":   map[string]interface{}{"name": "backup-creds", "namespace": "app-prod"},
+					"type":       "Opaque",
+				},
+			},
+		},
 	}
 }
 
@@ -67,3 +87,31 @@ func (m *MockClient) ListCNPGClusters(context string, namespace string) ([]strin
 	}
 	return clusters, nil
 }
+
+func (m *MockClient) Lookup(context, apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	items := []interface{}{}
+	for _, obj := range m.Objects[context] {
+		if obj["apiVersion"] != apiVersion || obj["kind"] != kind {
+			continue
+		}
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		if namespace != "" && metadata["namespace"] != namespace {
+			continue
+		}
+		if name == "" {
+			items = append(items, obj)
+			continue
+		}
+		if metadata["name"] == name {
+			return obj, nil
+		}
+	}
+	if name != "" {
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind + "List",
+		"items":      items,
+	}, nil
+}
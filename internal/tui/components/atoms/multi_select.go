@@ -0,0 +1,11 @@
+package atoms
+
+import "github.com/charmbracelet/huh"
+
+// StyledMultiSelect creates a consistently styled multi-select field.
+func StyledMultiSelect(title string, options []huh.Option[string], value *[]string) *huh.MultiSelect[string] {
+	return huh.NewMultiSelect[string]().
+		Title(title).
+		Options(options...).
+		Value(value)
+}
@@ -0,0 +1,11 @@
+package atoms
+
+import "github.com/charmbracelet/huh"
+
+// StyledText creates a consistently styled multi-line text field.
+func StyledText(title, placeholder string, value *string) *huh.Text {
+	return huh.NewText().
+		Title(title).
+		Placeholder(placeholder).
+		Value(value)
+}
@@ -20,28 +20,28 @@ func K8sContextSelect(client domain.KubeClient, value *string) *huh.Select[strin
 	return atoms.StyledSelect("Kubernetes Context", options, value)
 }
 
-// K8sNamespaceSelect creates a select field populated with namespaces for a given context.
-func K8sNamespaceSelect(client domain.KubeClient, context string, value *string) *huh.Select[string] {
-	namespaces, err := client.ListNamespaces(context)
-	if err != nil {
-		namespaces = []string{}
-	}
-	options := make([]huh.Option[string], len(namespaces))
-	for i, ns := range namespaces {
-		options[i] = huh.NewOption(ns, ns)
-	}
-	return atoms.StyledSelect("Namespace", options, value)
+// K8sNamespaceSelect creates a select field populated with namespaces for the context
+// context points to, listed again whenever it changes earlier in the form.
+func K8sNamespaceSelect(client domain.KubeClient, context *string, value *string) *huh.Select[string] {
+	return atoms.StyledSelect("Namespace", nil, value).
+		OptionsFunc(func() []huh.Option[string] {
+			namespaces, err := client.ListNamespaces(*context)
+			if err != nil {
+				return nil
+			}
+			return huh.NewOptions(namespaces...)
+		}, context)
 }
 
-// K8sCNPGClusterSelect creates a select field populated with CNPG clusters.
-func K8sCNPGClusterSelect(client domain.KubeClient, context, namespace string, value *string) *huh.Select[string] {
-	clusters, err := client.ListCNPGClusters(context, namespace)
-	if err != nil {
-		clusters = []string{}
-	}
-	options := make([]huh.Option[string], len(clusters))
-	for i, c := range clusters {
-		options[i] = huh.NewOption(c, c)
-	}
-	return atoms.StyledSelect("CNPG Cluster", options, value)
+// K8sCNPGClusterSelect creates a select field populated with CNPG clusters in the
+// namespace namespace points to, listed again whenever it or the context changes.
+func K8sCNPGClusterSelect(client domain.KubeClient, context, namespace *string, value *string) *huh.Select[string] {
+	return atoms.StyledSelect("CNPG Cluster", nil, value).
+		OptionsFunc(func() []huh.Option[string] {
+			clusters, err := client.ListCNPGClusters(*context, *namespace)
+			if err != nil {
+				return nil
+			}
+			return huh.NewOptions(clusters...)
+		}, []*string{context, namespace})
 }
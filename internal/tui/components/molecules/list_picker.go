@@ -7,16 +7,37 @@ import (
 	"github.com/charmbracelet/huh"
 )
 
+// ItemFilter decides which static list items are offered. Bindings are the values
+// the decision depends on; the options are re-evaluated whenever they change.
+type ItemFilter struct {
+	Offered  func(list *domain.StaticListMeta) []string
+	Bindings any
+}
+
 // ListPicker creates a select field with items from a static list.
-func ListPicker(registry domain.TemplateRegistry, listName string, value *string) *huh.Select[string] {
-	list, err := registry.GetStaticList(listName)
+// An empty value pre-selects the field's template default. With a filter,
+// only the items it offers for the current answers are shown.
+func ListPicker(registry domain.TemplateRegistry, def domain.FieldDefinition, value *string, filter *ItemFilter) *huh.Select[string] {
+	if *value == "" {
+		*value = def.Default
+	}
+
+	list, err := registry.GetStaticList(def.Source)
 	if err != nil {
-		return atoms.StyledSelect(listName, nil, value)
+		return atoms.StyledSelect(def.Title(), nil, value).Description(def.Description)
+	}
+
+	if filter != nil {
+		return atoms.StyledSelect(def.Title(), nil, value).
+			Description(def.Description).
+			OptionsFunc(func() []huh.Option[string] {
+				return huh.NewOptions(filter.Offered(list)...)
+			}, filter.Bindings)
 	}
 
 	options := make([]huh.Option[string], len(list.Items))
 	for i, item := range list.Items {
 		options[i] = huh.NewOption(item, item)
 	}
-	return atoms.StyledSelect(listName, options, value)
+	return atoms.StyledSelect(def.Title(), options, value).Description(def.Description)
 }
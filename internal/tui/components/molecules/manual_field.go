@@ -1,21 +1,60 @@
 package molecules
 
 import (
+	"time"
+
 	"inscribe/internal/domain"
 	"inscribe/internal/tui/components/atoms"
 
 	"github.com/charmbracelet/huh"
 )
 
-// ManualField creates a text input with domain validation for a manual field.
-func ManualField(def domain.FieldDefinition, value *string) *huh.Input {
-	input := atoms.StyledInput(def.Name, "Enter "+def.Name, value)
+// ManualField creates a text input with domain validation for a manual field, and
+// check, if not nil, for a valid value. An empty value is pre-filled with the field's
+// template default; optional fields may be left empty. A cron schedule's next runs
+// are shown below its description as it is typed.
+func ManualField(def domain.FieldDefinition, value *string, check func(string) error) *huh.Input {
+	if *value == "" {
+		*value = def.Default
+	}
+	placeholder := def.Placeholder
+	if placeholder == "" {
+		placeholder = "Enter " + def.Name
+	}
+	input := atoms.StyledInput(def.Title(), placeholder, value).
+		Description(def.Description)
+	if domain.ValidationTypeName(def.ValidationType) == "cron-schedule" {
+		input = input.DescriptionFunc(func() string {
+			upcoming := domain.CronUpcoming(def.ValidationType, *value, time.Now())
+			if upcoming == "" {
+				return def.Description
+			}
+			if def.Description == "" {
+				return "Next runs: " + upcoming
+			}
+			return def.Description + "\nNext runs: " + upcoming
+		}, value)
+	}
 
-	if def.ValidationType != "" {
-		vt := def.ValidationType
+	if def.ValidationType != "" || check != nil {
+		validator, typeErr := domain.NewValidator(def.ValidationType)
+		optional := def.Optional
 		input = input.Validate(func(s string) error {
-			_, err := domain.ParseValue(vt, s)
-			return err
+			if optional && s == "" {
+				return nil
+			}
+			if def.ValidationType != "" {
+				if typeErr != nil {
+					return typeErr
+				}
+				if _, err := validator.Parse(s); err != nil {
+					return err
+				}
+			}
+			if check != nil {
+				return check(s)
+			}
+			return nil
 		})
 	}
 
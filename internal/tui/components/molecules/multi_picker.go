@@ -0,0 +1,54 @@
+package molecules
+
+import (
+	"fmt"
+
+	"inscribe/internal/domain"
+	"inscribe/internal/tui/components/atoms"
+
+	"github.com/charmbracelet/huh"
+)
+
+// MultiListPicker creates a multi-select field with items from a static list.
+// With a filter, only the items it offers for the current answers are shown.
+func MultiListPicker(registry domain.TemplateRegistry, def domain.FieldDefinition, selected *[]string, filter *ItemFilter) *huh.MultiSelect[string] {
+	list, err := registry.GetStaticList(def.Source)
+	if err != nil {
+		return multiPicker(def, nil, selected)
+	}
+	if filter != nil {
+		return multiPicker(def, nil, selected).
+			OptionsFunc(func() []huh.Option[string] {
+				return huh.NewOptions(filter.Offered(list)...)
+			}, filter.Bindings)
+	}
+	return multiPicker(def, huh.NewOptions(list.Items...), selected)
+}
+
+// MultiTemplatePicker creates a multi-select field with sub-template options.
+// Selections are kept as sub-template descriptions, which the renderer resolves.
+func MultiTemplatePicker(registry domain.TemplateRegistry, def domain.FieldDefinition, selected *[]string) *huh.MultiSelect[string] {
+	var options []huh.Option[string]
+	if subs, err := registry.GetSubTemplates(def.Source); err == nil {
+		for _, sub := range subs {
+			options = append(options, huh.NewOption(sub.Description, sub.Description))
+		}
+	}
+	return multiPicker(def, options, selected)
+}
+
+// multiPicker binds a multi-select to selected. An empty selection pre-selects
+// the field's template default.
+func multiPicker(def domain.FieldDefinition, options []huh.Option[string], selected *[]string) *huh.MultiSelect[string] {
+	if len(*selected) == 0 {
+		*selected = domain.SplitList(def.Default)
+	}
+	return atoms.StyledMultiSelect(def.Title(), options, selected).
+		Description(def.Description).
+		Validate(func(sel []string) error {
+			if len(sel) == 0 && !def.Optional {
+				return fmt.Errorf("select at least one option")
+			}
+			return nil
+		})
+}
@@ -0,0 +1,42 @@
+package molecules
+
+import (
+	"fmt"
+
+	"inscribe/internal/domain"
+	"inscribe/internal/tui/components/atoms"
+
+	"github.com/charmbracelet/huh"
+)
+
+// RepeatableField creates a multi-line text area for a repeatable field, one entry per line,
+// validating every entry. An empty value is pre-filled with the field's template default.
+func RepeatableField(def domain.FieldDefinition, value *string) *huh.Text {
+	if *value == "" {
+		*value = def.Default
+	}
+	placeholder := def.Placeholder
+	if placeholder == "" {
+		placeholder = "One " + def.Name + " entry per line"
+	}
+	if schema, ok := domain.ParseObjectSchema(def.ValidationType); ok && def.Placeholder == "" {
+		placeholder = "One entry per line, e.g. "
+		for i, field := range schema {
+			if i > 0 {
+				placeholder += ","
+			}
+			placeholder += field.Name + "=..."
+		}
+	}
+
+	vt := def.ValidationType
+	optional := def.Optional
+	return atoms.StyledText(def.Title(), placeholder, value).
+		Description(def.Description).
+		Validate(func(s string) error {
+			if len(domain.SplitList(s)) == 0 && !optional {
+				return fmt.Errorf("at least one entry is required")
+			}
+			return domain.ValidateList(vt, s)
+		})
+}
@@ -1,6 +1,8 @@
 package molecules
 
 import (
+	"strings"
+
 	"inscribe/internal/domain"
 	"inscribe/internal/tui/components/atoms"
 
@@ -8,15 +10,19 @@ import (
 )
 
 // TemplatePicker creates a select field with sub-template options, showing descriptions.
-func TemplatePicker(registry domain.TemplateRegistry, group string, value *string) *huh.Select[string] {
-	subs, err := registry.GetSubTemplates(group)
+// An empty value pre-selects the sub-template whose description matches the field's default.
+func TemplatePicker(registry domain.TemplateRegistry, def domain.FieldDefinition, value *string) *huh.Select[string] {
+	subs, err := registry.GetSubTemplates(def.Source)
 	if err != nil {
-		return atoms.StyledSelect(group, nil, value)
+		return atoms.StyledSelect(def.Title(), nil, value).Description(def.Description)
 	}
 
 	options := make([]huh.Option[string], len(subs))
 	for i, sub := range subs {
 		options[i] = huh.NewOption(sub.Description, sub.Content)
+		if *value == "" && def.Default != "" && strings.EqualFold(sub.Description, def.Default) {
+			*value = sub.Content
+		}
 	}
-	return atoms.StyledSelect(group, options, value)
+	return atoms.StyledSelect(def.Title(), options, value).Description(def.Description)
 }
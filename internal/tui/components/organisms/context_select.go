@@ -14,9 +14,10 @@ func ContextSelectGroup(client domain.KubeClient, context *string) *huh.Group {
 	).Title("Kubernetes Connection")
 }
 
-// NamespaceSelectGroup creates a form group for selecting a namespace after context is chosen.
-func NamespaceSelectGroup(client domain.KubeClient, context string, namespace *string) *huh.Group {
+// NamespaceSelectGroup creates a form group for selecting a namespace of the context
+// chosen earlier in the form. The title names what the namespace is for.
+func NamespaceSelectGroup(client domain.KubeClient, context *string, title string, namespace *string) *huh.Group {
 	return huh.NewGroup(
-		molecules.K8sNamespaceSelect(client, context, namespace),
+		molecules.K8sNamespaceSelect(client, context, namespace).Title(title),
 	).Title("Namespace Selection")
 }
@@ -1,15 +1,30 @@
 package organisms
 
 import (
+	"slices"
+
 	"inscribe/internal/domain"
 	"inscribe/internal/tui/components/molecules"
 
 	"github.com/charmbracelet/huh"
 )
 
-// FieldGroup creates a form group from field definitions, skipping autoList fields
-// (which are handled separately by context/namespace selection).
-func FieldGroup(defs []domain.FieldDefinition, values map[string]*string, registry domain.TemplateRegistry) *huh.Group {
+// KubeLookup lists cluster resources for autoList fields. Context points to the
+// Kubernetes context chosen in the form; ClusterNamespace returns the answer holding
+// the namespace a cnpg-clusters field lists clusters from.
+type KubeLookup struct {
+	Client           domain.KubeClient
+	Context          *string
+	ClusterNamespace func(field string) *string
+}
+
+// FieldGroup creates a form group titled after page from field definitions. Static
+// list items with show rules are offered according to the answers in values as they
+// change, and inputs are checked against the rules pointing at them. Multi-select
+// fields are bound to their entry in selections, which is created from the answer in
+// values if missing; the caller joins the selections into values once the form has
+// run. AutoList fields are listed through kube, or skipped if it is nil.
+func FieldGroup(page domain.Page, defs []domain.FieldDefinition, values map[string]*string, selections map[string]*[]string, registry domain.TemplateRegistry, evaluator domain.Evaluator, rules []domain.Rule, kube *KubeLookup) *huh.Group {
 	var fields []huh.Field
 	for _, def := range defs {
 		val, ok := values[def.Name]
@@ -18,19 +33,158 @@ func FieldGroup(defs []domain.FieldDefinition, values map[string]*string, regist
 			values[def.Name] = &s
 			val = values[def.Name]
 		}
+		if def.Multiple && selections[def.Name] == nil {
+			sel := domain.SplitList(*val)
+			selections[def.Name] = &sel
+		}
 
 		switch def.Type {
 		case domain.FieldInput:
-			fields = append(fields, molecules.ManualField(def, val))
+			fields = append(fields, molecules.ManualField(def, val, ruleCheck(def, values, selections, evaluator, rules)))
+		case domain.FieldRepeatable:
+			fields = append(fields, molecules.RepeatableField(def, val))
 		case domain.FieldTemplateGroup:
-			fields = append(fields, molecules.TemplatePicker(registry, def.Source, val))
+			if def.Multiple {
+				fields = append(fields, molecules.MultiTemplatePicker(registry, def, selections[def.Name]))
+				continue
+			}
+			fields = append(fields, molecules.TemplatePicker(registry, def, val))
 		case domain.FieldStaticList:
-			fields = append(fields, molecules.ListPicker(registry, def.Source, val))
+			filter := itemFilter(registry, def, values, selections, evaluator)
+			if def.Multiple {
+				fields = append(fields, molecules.MultiListPicker(registry, def, selections[def.Name], filter))
+				continue
+			}
+			fields = append(fields, molecules.ListPicker(registry, def, val, filter))
 		case domain.FieldAutoList:
-			// Handled by ContextSelectGroup / NamespaceSelectGroup
+			if field := autoListSelect(kube, def, val); field != nil {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	return huh.NewGroup(fields...).Title(page.DisplayTitle()).Description(page.Description)
+}
+
+// FieldGroups splits the fields of a page into form groups so conditional fields can
+// be hidden. Consecutive fields sharing a condition are grouped together; a group whose
+// condition does not hold for the values collected so far is skipped.
+func FieldGroups(page domain.Page, defs []domain.FieldDefinition, values map[string]*string, selections map[string]*[]string, registry domain.TemplateRegistry, evaluator domain.Evaluator, rules []domain.Rule, kube *KubeLookup) []*huh.Group {
+	var groups []*huh.Group
+	start := 0
+	for i := 1; i <= len(defs); i++ {
+		if i < len(defs) && defs[i].Condition == defs[start].Condition {
 			continue
 		}
+		group := FieldGroup(page, defs[start:i], values, selections, registry, evaluator, rules, kube)
+		if cond := defs[start].Condition; cond != "" {
+			group = group.WithHideFunc(func() bool {
+				applies, err := evaluator.EvalCondition(cond, snapshot(values, selections))
+				return err == nil && !applies
+			})
+		}
+		groups = append(groups, group)
+		start = i
+	}
+	return groups
+}
+
+// ruleCheck returns a check of an answer to def against the rules pointing at it,
+// given the other answers so far, or nil if no rule does.
+func ruleCheck(def domain.FieldDefinition, values map[string]*string, selections map[string]*[]string, checker domain.RuleChecker, rules []domain.Rule) func(string) error {
+	var own []domain.Rule
+	for _, rule := range rules {
+		if slices.Contains(rule.Fields, def.Name) {
+			own = append(own, rule)
+		}
+	}
+	if len(own) == 0 {
+		return nil
+	}
+	return func(s string) error {
+		current := snapshot(values, selections)
+		current[def.Name] = s
+		for _, rule := range own {
+			if err := checker.CheckRule(rule, current); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
+}
+
+// autoListSelect creates the select for an autoList field, pre-selecting its default,
+// or returns nil if its source cannot be listed.
+func autoListSelect(kube *KubeLookup, def domain.FieldDefinition, value *string) huh.Field {
+	if kube == nil {
+		return nil
+	}
+	if *value == "" {
+		*value = def.Default
+	}
+	switch def.Source {
+	case "namespace":
+		return molecules.K8sNamespaceSelect(kube.Client, kube.Context, value).
+			Title(autoListTitle(def, "Namespace")).
+			Description(def.Description)
+	case "cnpg-clusters":
+		return molecules.K8sCNPGClusterSelect(kube.Client, kube.Context, kube.ClusterNamespace(def.Name), value).
+			Title(autoListTitle(def, "CNPG Cluster")).
+			Description(def.Description)
+	}
+	return nil
+}
+
+// autoListTitle returns the prompt title for an autoList field: its label, or its name
+// when it is an alias, or fallback when the field is named after its source.
+func autoListTitle(f domain.FieldDefinition, fallback string) string {
+	if f.Label == "" && f.Name == f.Source {
+		return fallback
+	}
+	return f.Title()
+}
 
-	return huh.NewGroup(fields...).Title("Template Fields")
+// itemFilter returns a filter offering the items of def's static list whose show rule
+// holds for the current answers, or nil if no item has a rule.
+func itemFilter(registry domain.TemplateRegistry, def domain.FieldDefinition, values map[string]*string, selections map[string]*[]string, evaluator domain.ConditionEvaluator) *molecules.ItemFilter {
+	list, err := registry.GetStaticList(def.Source)
+	if err != nil || len(list.DependsOn) == 0 {
+		return nil
+	}
+	bindings := make(map[string]any, len(list.DependsOn))
+	for _, name := range list.DependsOn {
+		if sel, ok := selections[name]; ok {
+			bindings[name] = sel
+		} else if ptr, ok := values[name]; ok {
+			bindings[name] = ptr
+		}
+	}
+	return &molecules.ItemFilter{
+		Offered: func(list *domain.StaticListMeta) []string {
+			current := snapshot(values, selections)
+			var items []string
+			for i, item := range list.Items {
+				applies, err := evaluator.EvalCondition(list.ItemConditions[i], current)
+				if err == nil && !applies {
+					continue
+				}
+				items = append(items, item)
+			}
+			return items
+		},
+		Bindings: bindings,
+	}
+}
+
+// snapshot copies the current answers so conditions can be evaluated against them,
+// with the current selections of multi-select fields.
+func snapshot(values map[string]*string, selections map[string]*[]string) map[string]string {
+	current := make(map[string]string, len(values))
+	for name, ptr := range values {
+		current[name] = *ptr
+	}
+	for name, sel := range selections {
+		current[name] = domain.JoinList(*sel)
+	}
+	return current
 }
@@ -5,7 +5,6 @@ import (
 
 	"inscribe/internal/domain"
 	"inscribe/internal/tui/components/atoms"
-	"inscribe/internal/tui/components/molecules"
 	"inscribe/internal/tui/components/organisms"
 
 	"github.com/charmbracelet/huh"
@@ -17,16 +16,24 @@ type WizardResult struct {
 	Filename string
 }
 
-// RunWizard orchestrates the TUI wizard flow:
-// 1. Context/namespace selection (if autoList fields exist)
-// 2. Field collection for remaining fields
-// 3. Filename input
+// RunWizard runs the TUI wizard as one form, so answers can be revisited with
+// next/back navigation before it completes:
+// 1. Context selection (if autoList fields exist)
+// 2. One page per declared page with fields assigned to it, after a default page
+// holding the remaining fields; fields whose condition does not hold are skipped, and
+// cross-field rules are checked on the fields they point at as they are answered
+// 3. Filename input, which may be left empty when filenameHint says what happens then
+// The form is shown again as long as its answers break a cross-field rule.
 func RunWizard(
 	fields []domain.FieldDefinition,
+	pages []domain.Page,
+	rules []domain.Rule,
 	prefilledValues map[string]string,
 	registry domain.TemplateRegistry,
 	client domain.KubeClient,
+	evaluator domain.Evaluator,
 	defaultFilename string,
+	filenameHint string,
 ) (*WizardResult, error) {
 	// Initialize value pointers map with pre-filled values
 	valuePtrs := make(map[string]*string)
@@ -37,102 +44,111 @@ func RunWizard(
 		}
 		valuePtrs[f.Name] = &s
 	}
+	// Multi-select fields are bound to their selections, which are joined into
+	// valuePtrs once the form has run.
+	selections := make(map[string]*[]string)
 
-	// Determine if we need k8s context selection
-	needsK8s := false
-	needsCNPGSelect := false
-	for _, f := range fields {
-		if f.Type == domain.FieldAutoList {
-			needsK8s = true
-			if f.Source == "cnpg-clusters" {
-				needsCNPGSelect = true
-			}
-		}
-	}
+	prompted := promptedFields(fields, prefilledValues)
 
-	var contextValue, namespaceValue string
+	var contextValue string
 	if v, ok := prefilledValues["context"]; ok {
 		contextValue = v
 	}
-	if v, ok := prefilledValues["namespace"]; ok {
-		namespaceValue = v
-	}
 
-	// Phase 1: Context selection (if needed and not pre-filled)
-	if needsK8s && contextValue == "" {
-		contextForm := huh.NewForm(
-			organisms.ContextSelectGroup(client, &contextValue),
-		).WithTheme(atoms.Theme())
-
-		if err := contextForm.Run(); err != nil {
-			return nil, fmt.Errorf("context selection: %w", err)
+	// Context selection, if a prompted field lists cluster resources
+	needsK8s := false
+	needsListingNamespace := false
+	for _, f := range prompted {
+		if f.Type != domain.FieldAutoList {
+			continue
 		}
-	}
-
-	// Phase 2: Namespace selection (if needed and not pre-filled)
-	if needsK8s && namespaceValue == "" {
-		nsForm := huh.NewForm(
-			organisms.NamespaceSelectGroup(client, contextValue, &namespaceValue),
-		).WithTheme(atoms.Theme())
-
-		if err := nsForm.Run(); err != nil {
-			return nil, fmt.Errorf("namespace selection: %w", err)
+		needsK8s = true
+		if f.Source == "cnpg-clusters" && !hasNamespaceField(fields) {
+			needsListingNamespace = true
 		}
 	}
 
-	// Set autoList values
-	if needsK8s {
-		if p, ok := valuePtrs["namespace"]; ok {
-			*p = namespaceValue
-		}
+	// CNPG clusters are listed in the namespace of the closest preceding namespace
+	// field. Without one, a namespace is asked for once.
+	listingNamespace := new(string)
+	kube := &organisms.KubeLookup{
+		Client:  client,
+		Context: &contextValue,
+		ClusterNamespace: func(name string) *string {
+			for i, f := range fields {
+				if f.Name != name {
+					continue
+				}
+				if ns, ok := clusterNamespace(fields, i, valuePtrs); ok {
+					return ns
+				}
+			}
+			return listingNamespace
+		},
 	}
 
-	// Phase 2.5: CNPG cluster selection (if needed)
-	if needsCNPGSelect {
-		if p, ok := valuePtrs["cnpg-clusters"]; ok && *p == "" {
-			var cnpgCluster string
-			cnpgForm := huh.NewForm(
-				huh.NewGroup(
-					molecules.K8sCNPGClusterSelect(client, contextValue, namespaceValue, &cnpgCluster),
-				).Title("CNPG Cluster Selection"),
-			).WithTheme(atoms.Theme())
+	filename := defaultFilename
+	askFilename := filename == ""
 
-			if err := cnpgForm.Run(); err != nil {
-				return nil, fmt.Errorf("CNPG cluster selection: %w", err)
-			}
-			*p = cnpgCluster
+	// The form is built anew when it is shown again, leading with the rules its
+	// answers broke.
+	buildGroups := func(ruleErr error) []*huh.Group {
+		var groups []*huh.Group
+		if ruleErr != nil {
+			groups = append(groups, huh.NewGroup(
+				huh.NewNote().Title("Some answers break the template's rules").Description(ruleErr.Error()),
+			).Title("Check your answers"))
+		}
+		if needsK8s && contextValue == "" {
+			groups = append(groups, organisms.ContextSelectGroup(client, &contextValue))
+		}
+		if needsListingNamespace {
+			groups = append(groups, organisms.NamespaceSelectGroup(client, &contextValue, "Namespace", listingNamespace))
 		}
-	}
 
-	// Phase 3: Collect remaining fields (manual, templateGroup, list)
-	remainingFields := filterNonAutoListFields(fields, prefilledValues)
-	if len(remainingFields) > 0 {
-		fieldGroup := organisms.FieldGroup(remainingFields, valuePtrs, registry)
-		fieldForm := huh.NewForm(fieldGroup).WithTheme(atoms.Theme())
+		// Field pages
+		for _, page := range wizardPages(prompted, pages) {
+			groups = append(groups, organisms.FieldGroups(page.page, page.fields, valuePtrs, selections, registry, evaluator, rules, kube)...)
+		}
 
-		if err := fieldForm.Run(); err != nil {
-			return nil, fmt.Errorf("field collection: %w", err)
+		// Filename
+		if askFilename {
+			filenameInput := huh.NewInput().
+				Title("Output filename").
+				Placeholder("manifest.yaml").
+				Value(&filename).
+				Validate(func(s string) error {
+					if filenameHint != "" && s == "" {
+						return nil
+					}
+					_, err := domain.NewFilename(s)
+					return err
+				})
+			if filenameHint != "" {
+				filenameInput = filenameInput.Description(filenameHint)
+			}
+			groups = append(groups, huh.NewGroup(filenameInput).Title("Output"))
 		}
+		return groups
 	}
 
-	// Phase 4: Filename
-	filename := defaultFilename
-	if filename == "" {
-		filenameForm := huh.NewForm(
-			huh.NewGroup(
-				huh.NewInput().
-					Title("Output filename").
-					Placeholder("manifest.yaml").
-					Value(&filename).
-					Validate(func(s string) error {
-						_, err := domain.NewFilename(s)
-						return err
-					}),
-			).Title("Output"),
-		).WithTheme(atoms.Theme())
-
-		if err := filenameForm.Run(); err != nil {
-			return nil, fmt.Errorf("filename input: %w", err)
+	// Rules are checked again on submit, as answers may have been changed after the
+	// fields a rule points at were answered.
+	var ruleErr error
+	for {
+		groups := buildGroups(ruleErr)
+		if len(groups) == 0 {
+			break
+		}
+		form := huh.NewForm(groups...).WithTheme(atoms.Theme())
+		if err := form.Run(); err != nil {
+			return nil, fmt.Errorf("collecting answers: %w", err)
+		}
+		for name, sel := range selections {
+			*valuePtrs[name] = domain.JoinList(*sel)
+		}
+		if ruleErr = evaluator.CheckRules(fields, rules, answers(fields, valuePtrs, contextValue), true); ruleErr == nil {
+			break
 		}
 	}
 
@@ -144,15 +160,35 @@ func RunWizard(
 	for name, ptr := range valuePtrs {
 		result.Values[name] = *ptr
 	}
+	// The chosen context is kept for rendering, where lookup reads from it.
+	if contextValue != "" {
+		result.Values["context"] = contextValue
+	}
 
 	return result, nil
 }
 
-// filterNonAutoListFields returns fields that are not autoList and not already pre-filled.
-func filterNonAutoListFields(fields []domain.FieldDefinition, prefilled map[string]string) []domain.FieldDefinition {
+// answers returns the answers so far for checking rules: those to fields other than
+// derived ones, which are computed later, and the chosen context.
+func answers(fields []domain.FieldDefinition, values map[string]*string, contextValue string) map[string]string {
+	result := make(map[string]string, len(fields)+1)
+	for _, f := range fields {
+		if f.Type != domain.FieldDerived {
+			result[f.Name] = *values[f.Name]
+		}
+	}
+	if contextValue != "" {
+		result["context"] = contextValue
+	}
+	return result
+}
+
+// promptedFields returns the fields the wizard asks for: those not already pre-filled.
+// Derived fields are never prompted, so they are excluded as well.
+func promptedFields(fields []domain.FieldDefinition, prefilled map[string]string) []domain.FieldDefinition {
 	var result []domain.FieldDefinition
 	for _, f := range fields {
-		if f.Type == domain.FieldAutoList {
+		if f.Type == domain.FieldDerived {
 			continue
 		}
 		if _, ok := prefilled[f.Name]; ok {
@@ -162,3 +198,68 @@ func filterNonAutoListFields(fields []domain.FieldDefinition, prefilled map[stri
 	}
 	return result
 }
+
+// wizardPage is a page of the wizard and the fields asked on it, in template order.
+type wizardPage struct {
+	page   domain.Page
+	fields []domain.FieldDefinition
+}
+
+// wizardPages distributes fields over the declared pages in declaration order.
+// Fields assigned to no page come first, on a default "Template Fields" page.
+// Pages without fields to ask are left out.
+func wizardPages(fields []domain.FieldDefinition, pages []domain.Page) []wizardPage {
+	result := []wizardPage{{page: domain.Page{Title: "Template Fields"}}}
+	index := map[string]int{"": 0}
+	for _, page := range pages {
+		index[page.Name] = len(result)
+		result = append(result, wizardPage{page: page})
+	}
+	for _, f := range fields {
+		i := index[f.Page]
+		result[i].fields = append(result[i].fields, f)
+	}
+
+	var nonEmpty []wizardPage
+	for _, page := range result {
+		if len(page.fields) > 0 {
+			nonEmpty = append(nonEmpty, page)
+		}
+	}
+	return nonEmpty
+}
+
+// hasNamespaceField reports whether the template asks for a namespace.
+func hasNamespaceField(fields []domain.FieldDefinition) bool {
+	for _, f := range fields {
+		if f.Type == domain.FieldAutoList && f.Source == "namespace" {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterNamespace returns the answer to the namespace field named by the namespace
+// option of fields[i], or else to the namespace field closest before it, or to the
+// first namespace field if none precedes it. It reports false when the template has
+// no such namespace field.
+func clusterNamespace(fields []domain.FieldDefinition, i int, values map[string]*string) (*string, bool) {
+	if name := fields[i].Namespace; name != "" {
+		ns, ok := values[name]
+		return ns, ok
+	}
+	isNamespace := func(f domain.FieldDefinition) bool {
+		return f.Type == domain.FieldAutoList && f.Source == "namespace"
+	}
+	for j := i - 1; j >= 0; j-- {
+		if isNamespace(fields[j]) {
+			return values[fields[j].Name], true
+		}
+	}
+	for _, f := range fields[i:] {
+		if isNamespace(f) {
+			return values[f.Name], true
+		}
+	}
+	return nil, false
+}
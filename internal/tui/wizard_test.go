@@ -1,12 +1,14 @@
 package tui
 
 import (
+	"maps"
+	"slices"
 	"testing"
 
 	"inscribe/internal/domain"
 )
 
-func TestFilterNonAutoListFields(t *testing.T) {
+func TestPromptedFields(t *testing.T) {
 	fields := []domain.FieldDefinition{
 		{Name: "name", Type: domain.FieldInput, ValidationType: "dns-name"},
 		{Name: "namespace", Type: domain.FieldAutoList, Source: "namespace"},
@@ -15,49 +17,161 @@ func TestFilterNonAutoListFields(t *testing.T) {
 		{Name: "cnpg-clusters", Type: domain.FieldAutoList, Source: "cnpg-clusters"},
 	}
 
-	t.Run("no prefilled values", func(t *testing.T) {
-		result := filterNonAutoListFields(fields, map[string]string{})
-		if len(result) != 3 {
-			t.Fatalf("expected 3 fields, got %d", len(result))
+	names := func(defs []domain.FieldDefinition) []string {
+		var result []string
+		for _, f := range defs {
+			result = append(result, f.Name)
 		}
-		if result[0].Name != "name" {
-			t.Errorf("result[0].Name = %q, want %q", result[0].Name, "name")
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		fields    []domain.FieldDefinition
+		prefilled map[string]string
+		want      []string
+	}{
+		{
+			name:      "no prefilled values",
+			fields:    fields,
+			prefilled: map[string]string{},
+			want:      []string{"name", "namespace", "resources", "method", "cnpg-clusters"},
+		},
+		{
+			name:      "with prefilled value",
+			fields:    fields,
+			prefilled: map[string]string{"name": "mydb", "namespace": "db"},
+			want:      []string{"resources", "method", "cnpg-clusters"},
+		},
+		{
+			name:   "all prefilled",
+			fields: fields,
+			prefilled: map[string]string{
+				"name":          "mydb",
+				"namespace":     "db",
+				"resources":     "prod",
+				"method":        "barman",
+				"cnpg-clusters": "orders",
+			},
+			want: nil,
+		},
+		{
+			name:      "derived fields excluded",
+			fields:    append(fields[:1:1], domain.FieldDefinition{Name: "service", Type: domain.FieldDerived}),
+			prefilled: map[string]string{},
+			want:      []string{"name"},
+		},
+		{
+			name:      "empty fields",
+			prefilled: map[string]string{},
+			want:      nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(promptedFields(tt.fields, tt.prefilled))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("promptedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnswers(t *testing.T) {
+	name, service := "orders", ""
+	fields := []domain.FieldDefinition{
+		{Name: "name", Type: domain.FieldInput},
+		{Name: "service", Type: domain.FieldDerived},
+	}
+	values := map[string]*string{"name": &name, "service": &service}
+
+	got := answers(fields, values, "prod-eu")
+	want := map[string]string{"name": "orders", "context": "prod-eu"}
+	if !maps.Equal(got, want) {
+		t.Errorf("answers() = %v, want %v", got, want)
+	}
+	if got := answers(fields, values, ""); !maps.Equal(got, map[string]string{"name": "orders"}) {
+		t.Errorf("answers() without context = %v", got)
+	}
+}
+
+func TestWizardPages(t *testing.T) {
+	pages := []domain.Page{
+		{Name: "storage", Title: "Storage"},
+		{Name: "monitoring", Title: "Monitoring"},
+		{Name: "backup", Title: "Backup", Description: "Where backups are stored"},
+	}
+	fields := []domain.FieldDefinition{
+		{Name: "bucket", Page: "backup"},
+		{Name: "name"},
+		{Name: "size", Page: "storage"},
+		{Name: "namespace", Type: domain.FieldAutoList, Source: "namespace"},
+		{Name: "storage-class", Page: "storage"},
+	}
+
+	got := wizardPages(fields, pages)
+
+	want := []struct {
+		title  string
+		fields []string
+	}{
+		{"Template Fields", []string{"name", "namespace"}},
+		{"Storage", []string{"size", "storage-class"}},
+		{"Backup", []string{"bucket"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d pages, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].page.DisplayTitle() != w.title {
+			t.Errorf("page %d title = %q, want %q", i, got[i].page.DisplayTitle(), w.title)
 		}
-		if result[1].Name != "resources" {
-			t.Errorf("result[1].Name = %q, want %q", result[1].Name, "resources")
+		var names []string
+		for _, f := range got[i].fields {
+			names = append(names, f.Name)
 		}
-		if result[2].Name != "method" {
-			t.Errorf("result[2].Name = %q, want %q", result[2].Name, "method")
+		if !slices.Equal(names, w.fields) {
+			t.Errorf("page %q fields = %v, want %v", w.title, names, w.fields)
 		}
-	})
+	}
+	if got[2].page.Description != "Where backups are stored" {
+		t.Errorf("backup page description = %q", got[2].page.Description)
+	}
 
-	t.Run("with prefilled value", func(t *testing.T) {
-		prefilled := map[string]string{"name": "mydb"}
-		result := filterNonAutoListFields(fields, prefilled)
-		if len(result) != 2 {
-			t.Fatalf("expected 2 fields, got %d", len(result))
-		}
-		if result[0].Name != "resources" {
-			t.Errorf("result[0].Name = %q, want %q", result[0].Name, "resources")
-		}
-	})
+	if got := wizardPages(fields[2:3], pages); len(got) != 1 || got[0].page.Name != "storage" {
+		t.Errorf("expected only the storage page without unassigned fields, got %+v", got)
+	}
+}
 
-	t.Run("all prefilled", func(t *testing.T) {
-		prefilled := map[string]string{
-			"name":      "mydb",
-			"resources": "prod",
-			"method":    "barman",
-		}
-		result := filterNonAutoListFields(fields, prefilled)
-		if len(result) != 0 {
-			t.Errorf("expected 0 fields, got %d", len(result))
-		}
-	})
+func TestClusterNamespace(t *testing.T) {
+	fields := []domain.FieldDefinition{
+		{Name: "cluster", Type: domain.FieldAutoList, Source: "cnpg-clusters"},
+		{Name: "source-ns", Type: domain.FieldAutoList, Source: "namespace"},
+		{Name: "source-cluster", Type: domain.FieldAutoList, Source: "cnpg-clusters"},
+		{Name: "target-ns", Type: domain.FieldAutoList, Source: "namespace"},
+		{Name: "target-cluster", Type: domain.FieldAutoList, Source: "cnpg-clusters"},
+		{Name: "replica-source", Type: domain.FieldAutoList, Source: "cnpg-clusters", Namespace: "source-ns"},
+	}
+	source, target := "staging", "production"
+	values := map[string]*string{"source-ns": &source, "target-ns": &target}
 
-	t.Run("empty fields", func(t *testing.T) {
-		result := filterNonAutoListFields(nil, map[string]string{})
-		if len(result) != 0 {
-			t.Errorf("expected 0 fields, got %d", len(result))
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "staging"},
+		{2, "staging"},
+		{4, "production"},
+		{5, "staging"},
+	}
+	for _, tt := range tests {
+		got, ok := clusterNamespace(fields, tt.index, values)
+		if !ok || *got != tt.want {
+			t.Errorf("clusterNamespace(%s) = %v, %v; want %q", fields[tt.index].Name, got, ok, tt.want)
 		}
-	})
+	}
+
+	if _, ok := clusterNamespace(fields[:1], 0, values); ok {
+		t.Error("expected no namespace without a namespace field")
+	}
 }